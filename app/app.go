@@ -4,24 +4,48 @@ package app
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"charm.land/huh/v2"
 	"go.followtheprocess.codes/msg"
 	"go.followtheprocess.codes/semver"
+	"go.followtheprocess.codes/tag/changelog"
 	"go.followtheprocess.codes/tag/config"
+	"go.followtheprocess.codes/tag/conventional"
 	"go.followtheprocess.codes/tag/git"
+	"go.followtheprocess.codes/tag/gomod"
 	"go.followtheprocess.codes/tag/hooks"
+	"go.followtheprocess.codes/tag/keypath"
 )
 
-// ErrAborted is returned whenever an action is aborted by the user.
-var ErrAborted = errors.New("Aborted")
+var (
+	// ErrAborted is returned whenever an action is aborted by the user.
+	ErrAborted = errors.New("Aborted")
+
+	// ErrNothingToRelease is returned by auto when none of the commits since
+	// the last tag warrant a new version.
+	ErrNothingToRelease = errors.New("no commits warrant a release")
+
+	// ErrVersionDrift is returned by check when the files or tags don't match
+	// the version in the config file.
+	ErrVersionDrift = errors.New("version drift detected")
+)
 
 const filePermissions = 0o644
 
@@ -30,6 +54,14 @@ type App struct {
 	Stdout      io.Writer
 	Stderr      io.Writer
 	Cfg         config.Config
+	Repo        git.Repo    // The git repo everything is done in
+	jsonOut     io.Writer   // Where to write JSON output, nil unless JSON output was asked for
+	result      *bumpResult // Record of the bump in progress, nil outside of a bump
+	tagPrefix   string      // Prefix of the tags being managed e.g. "api/", empty unless a module is selected
+	modulePath  string      // Directory of the selected module, empty for the whole repo
+	dir         string      // Directory of the config file (or the working directory without one), paths are relative to this
+	configFile  string      // Name of the config file in dir, only if replaceMode
+	versionKey  string      // Key of the version in the config file
 	replaceMode bool
 }
 
@@ -40,40 +72,190 @@ const (
 	major bumpType = iota
 	minor
 	patch
+	pre
+	release
 )
 
+// String implements [fmt.Stringer] for a bumpType.
+func (b bumpType) String() string {
+	switch b {
+	case major:
+		return "major"
+	case minor:
+		return "minor"
+	case patch:
+		return "patch"
+	case pre:
+		return "pre"
+	case release:
+		return "release"
+	default:
+		return fmt.Sprintf("bumpType(%d)", int(b))
+	}
+}
+
+// BumpOptions are the options common to all the bump commands.
+type BumpOptions struct {
+	Pre                 string // Pre-release label e.g. "rc", empty means a normal bump
+	Remote              string // Remote to push to, overrides the config file
+	Push                bool   // Push the tag to the remote
+	Force               bool   // Bypass the confirmation prompt
+	DryRun              bool   // Print what would have happened
+	AllowEmptyChangelog bool   // Allow bumping with an empty Unreleased changelog section
+}
+
+// UndoOptions are the options to the undo command.
+type UndoOptions struct {
+	Remote bool // Also delete the tag from the remote if it was pushed
+	Force  bool // Bypass the confirmation prompt
+	DryRun bool // Print what would have happened
+}
+
+// NextOptions are the options to the next command.
+type NextOptions struct {
+	Pre    string // Pre-release label e.g. "rc", as for the bump commands
+	Format string // Go template for the output, empty means just the version
+}
+
+// prereleaseLabel is the allowed format of a pre-release label, the counter
+// is managed by tag and appended after a '.'.
+var prereleaseLabel = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
 // New constructs and returns a new App.
-func New(cwd string, stdout, stderr io.Writer) (App, error) {
-	path := filepath.Join(cwd, config.Filename)
-	replaceMode := true
-	cfg, err := config.Load(path)
-	if err != nil {
-		if !errors.Is(err, config.ErrNoConfigFile) {
+//
+// The config file is the one at configPath (relative to cwd) if given, otherwise
+// the first one found searching up from cwd to the root of the repo, see [config.Find].
+// If there is one, everything tag does (the paths in it, git and hooks) is relative
+// to the directory it's in.
+func New(cwd, configPath string, stdout, stderr io.Writer) (App, error) {
+	path := configPath
+	if path == "" {
+		found, err := config.Find(cwd)
+		if err != nil && !errors.Is(err, config.ErrNoConfigFile) {
+			return App{}, err
+		}
+		path = found
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+
+	replaceMode := path != ""
+	var cfg config.Config
+	configFile := ""
+	if replaceMode {
+		var err error
+		cfg, err = config.Load(path)
+		if err != nil {
+			if errors.Is(err, config.ErrNoConfigFile) {
+				return App{}, fmt.Errorf("config file %s not found", path)
+			}
 			return App{}, err
 		}
 
-		replaceMode = false
+		// From here on, everything happens alongside the config file
+		cwd, configFile = filepath.Dir(path), filepath.Base(path)
+
+		// Catch mistakes now rather than part way through a bump
+		if err := cfg.Validate(); err != nil {
+			return App{}, fmt.Errorf("invalid config file %s:\n%w", path, err)
+		}
 	}
 
 	app := App{
 		Stdout:      stdout,
 		Stderr:      stderr,
 		Cfg:         cfg,
+		Repo:        git.Open(cwd),
+		dir:         cwd,
+		configFile:  configFile,
+		versionKey:  "version",
 		replaceMode: replaceMode,
 	}
 
 	return app, nil
 }
 
+// WithModule returns a copy of the App that manages the [[module]] selected by
+// name rather than the top level project, an empty name returns the App unchanged.
+//
+// The module's version, files, changelog and hooks take the place of the top level
+// ones, and only tags with the module's prefix are considered.
+func (a App) WithModule(name string) (App, error) {
+	if name == "" {
+		return a, nil
+	}
+	if !a.replaceMode {
+		return App{}, fmt.Errorf("cannot select module %q without a %s file", name, config.Filename)
+	}
+
+	module, index, err := a.Cfg.Module(name)
+	if err != nil {
+		return App{}, err
+	}
+
+	files := make([]config.File, 0, len(module.Files))
+	for _, file := range module.Files {
+		file.Path = path.Join(module.Path, file.Path)
+		files = append(files, file)
+	}
+
+	changelog := module.Changelog
+	if changelog.Path != "" {
+		changelog.Path = path.Join(module.Path, changelog.Path)
+	}
+
+	goCfg := module.Go
+	goCfg.Dir = path.Join(module.Path, goCfg.Dir)
+
+	a.Cfg.Version = module.Version
+	a.Cfg.Changelog = changelog
+	a.Cfg.Go = goCfg
+	a.Cfg.Hooks = module.Hooks
+	a.Cfg.Files = files
+	a.Cfg.Modules = nil
+	a.tagPrefix = module.TagPrefix()
+	a.modulePath = module.Path
+	a.versionKey = fmt.Sprintf("module.%d.version", index)
+
+	return a, nil
+}
+
+// WithJSON returns a copy of the App that outputs JSON rather than text, if enabled
+// is false the App is returned unchanged.
+//
+// Only the JSON is written to stdout, so it can always be parsed, any messages
+// about progress go to stderr instead.
+func (a App) WithJSON(enabled bool) App {
+	if !enabled {
+		return a
+	}
+	a.jsonOut = a.Stdout
+	a.Stdout = a.Stderr
+	return a
+}
+
 // List handles the list subcommand.
-func (a App) List(limit int) error {
-	if err := a.ensureRepo(); err != nil {
+func (a App) List(ctx context.Context, limit int) error {
+	if err := a.ensureRepo(ctx); err != nil {
 		return err
 	}
 	if limit <= 0 {
 		return errors.New("--limit must be a positive integer")
 	}
-	tags, limitHit, err := git.ListTags(limit)
+
+	if a.jsonOut != nil {
+		tags, err := a.Repo.Tags(ctx, a.tagPrefix)
+		if err != nil {
+			return err
+		}
+		out := make([]tagJSON, 0, min(limit, len(tags)))
+		for _, tag := range tags[:min(limit, len(tags))] {
+			out = append(out, newTagJSON(tag, a.tagPrefix))
+		}
+		return a.writeJSON(out)
+	}
+
+	tags, limitHit, err := a.Repo.ListTags(ctx, a.tagPrefix, limit)
 	if err != nil {
 		return err
 	}
@@ -88,18 +270,176 @@ func (a App) List(limit int) error {
 }
 
 // Latest handles the latest subcommand.
-func (a App) Latest() error {
-	if err := a.ensureRepo(); err != nil {
+func (a App) Latest(ctx context.Context) error {
+	if err := a.ensureRepo(ctx); err != nil {
 		return err
 	}
-	tag, err := git.LatestTag()
+	tag, err := a.Repo.LatestTag(ctx, a.tagPrefix)
 	if err != nil {
 		return err
 	}
+
+	if a.jsonOut != nil {
+		info, err := a.Repo.Tag(ctx, tag)
+		if err != nil {
+			return err
+		}
+		return a.writeJSON(newTagJSON(info, a.tagPrefix))
+	}
+
 	fmt.Fprintln(a.Stdout, tag)
 	return nil
 }
 
+// Verify handles the verify subcommand, checking the signature on tag and reporting
+// who signed it. If tag is empty, the latest tag is verified.
+func (a App) Verify(ctx context.Context, tag string) error {
+	if err := a.ensureRepo(ctx); err != nil {
+		return err
+	}
+
+	if tag == "" {
+		latest, err := a.Repo.LatestTag(ctx, a.tagPrefix)
+		if err != nil {
+			return err
+		}
+		tag = latest
+	}
+
+	verification, err := a.Repo.VerifyTag(ctx, tag)
+	if err != nil {
+		return err
+	}
+
+	if verification.Signer == "" {
+		msg.Fsuccess(a.Stdout, "Tag %s has a good signature", tag)
+		fmt.Fprintln(a.Stdout, verification.Output)
+		return nil
+	}
+
+	msg.Fsuccess(a.Stdout, "Tag %s has a good signature from %s", tag, verification.Signer)
+	return nil
+}
+
+// Check handles the check subcommand, making sure every configured file and the
+// latest tag agree with the version in the config file. Nothing is changed.
+func (a App) Check(ctx context.Context) error {
+	if err := a.ensureRepo(ctx); err != nil {
+		return err
+	}
+	if !a.replaceMode {
+		return fmt.Errorf("nothing to check, no %s file found", config.Filename)
+	}
+	if err := a.ensureFiles(); err != nil {
+		return err
+	}
+
+	version, err := semver.Parse(a.Cfg.Version)
+	if err != nil {
+		return fmt.Errorf("bad version in %s: %w", a.configFile, err)
+	}
+
+	// Render a copy, Next doesn't matter here as nothing is replaced
+	cfg := a.Cfg
+	if err := cfg.Render(version.String(), version.String(), a.captured()); err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, file := range cfg.Files {
+		problems += a.checkFile(ctx, file)
+	}
+
+	want := a.tagPrefix + version.Tag()
+	latest, err := a.Repo.LatestTag(ctx, a.tagPrefix)
+	switch {
+	case errors.Is(err, git.ErrNoTagsFound):
+		msg.Ferror(a.Stdout, "No tags found, expected %s", want)
+		problems++
+	case err != nil:
+		return err
+	case latest != want:
+		msg.Ferror(a.Stdout, "Latest tag is %s, expected %s", latest, want)
+		problems++
+	default:
+		msg.Fsuccess(a.Stdout, "Latest tag is %s", latest)
+		message, err := a.Repo.TagMessage(ctx, latest)
+		if err != nil {
+			return err
+		}
+		// Not drift as such, the tag is right but e.g. the template has changed since
+		if expected := strings.TrimSpace(cfg.Git.TagTemplate); message != expected {
+			msg.Fwarn(a.Stdout, "Tag %s has message %q, tag-template gives %q", latest, message, expected)
+		}
+	}
+
+	if problems != 0 {
+		return fmt.Errorf("%w: %d problem(s) found", ErrVersionDrift, problems)
+	}
+
+	msg.Fsuccess(a.Stdout, "Everything matches version %s", version)
+	return nil
+}
+
+// checkFile is a helper that checks a single (rendered) file entry contains the current
+// version, reporting on each file it refers to and returning the number of problems.
+func (a App) checkFile(ctx context.Context, file config.File) int {
+	paths, err := a.expandPaths(ctx, file.Path)
+	if err != nil {
+		msg.Ferror(a.Stdout, "%s: %v", file.Path, err)
+		return 1
+	}
+
+	problems := 0
+	total := 0
+	for _, path := range paths {
+		single := file
+		single.Path = path
+
+		contents, err := os.ReadFile(a.path(path))
+		if err != nil {
+			msg.Ferror(a.Stdout, "%s: %v", path, err)
+			problems++
+			continue
+		}
+
+		_, count, err := replaceContents(single, contents)
+		switch {
+		case err != nil:
+			msg.Ferror(a.Stdout, "%v", err)
+			problems++
+		case count == 0 && !isGlob(file.Path):
+			msg.Ferror(a.Stdout, "Could not find %q in %s", file.Search, path)
+			problems++
+		case count != 0:
+			msg.Fsuccess(a.Stdout, "Found %d occurrence(s) of %q in %s", count, file.Search, path)
+		}
+		total += count
+	}
+
+	if isGlob(file.Path) && total == 0 && problems == 0 {
+		msg.Ferror(a.Stdout, "Could not find %q in any of the %d file(s) matching %s", file.Search, len(paths), file.Path)
+		problems++
+	}
+
+	return problems
+}
+
+// ValidateConfig handles the config validate subcommand.
+//
+// The config file is validated by New, so if we got this far all that's
+// left is to make sure every [[file]] exists and say so.
+func (a App) ValidateConfig(cwd string) error {
+	if !a.replaceMode {
+		return fmt.Errorf("no %s file found in %s or above it", config.Filename, cwd)
+	}
+	if err := a.ensureFiles(); err != nil {
+		return err
+	}
+	msg.Fsuccess(a.Stdout, "%s is valid", a.path(a.configFile))
+	return nil
+}
+
 // Init handles the init subcommand.
 func (a App) Init(cwd string, force bool) error {
 	path := filepath.Join(cwd, config.Filename)
@@ -138,241 +478,1195 @@ func (a App) Init(cwd string, force bool) error {
 	return nil
 }
 
-// TODO: When it rewrites the config back, it does the rendered config with all
-// the .Current and .Next set to the actual values
-// Read the config in from scratch so it's not rendered (or make a new one)
-// and then only update the version before saving back
-
 // Major handles the major subcommand.
-func (a App) Major(push, force, dryRun bool) error {
-	return a.bump(major, push, force, dryRun)
+func (a App) Major(ctx context.Context, options BumpOptions) error {
+	return a.bump(ctx, major, options)
 }
 
 // Minor handles the minor subcommand.
-func (a App) Minor(push, force, dryRun bool) error {
-	return a.bump(minor, push, force, dryRun)
+func (a App) Minor(ctx context.Context, options BumpOptions) error {
+	return a.bump(ctx, minor, options)
 }
 
 // Patch handles the minor subcommand.
-func (a App) Patch(push, force, dryRun bool) error {
-	return a.bump(patch, push, force, dryRun)
+func (a App) Patch(ctx context.Context, options BumpOptions) error {
+	return a.bump(ctx, patch, options)
 }
 
-// replaceAll is a helper that performs and reports on file replacement
-// as part of bumping.
-func (a App) replaceAll(current, next semver.Version, dryRun bool) error {
-	originalConfig := a.Cfg
-	if err := a.Cfg.Render(current.String(), next.String()); err != nil {
-		return err
-	}
+// Pre handles the pre subcommand.
+func (a App) Pre(ctx context.Context, options BumpOptions) error {
+	return a.bump(ctx, pre, options)
+}
 
-	if err := a.replace(dryRun); err != nil {
-		return err
-	}
+// Release handles the release subcommand.
+func (a App) Release(ctx context.Context, options BumpOptions) error {
+	return a.bump(ctx, release, options)
+}
 
-	// Also replace the Version in the config file
-	originalConfig.Version = next.String()
-	if !dryRun {
-		if err := originalConfig.Save(config.Filename); err != nil {
-			return err
-		}
+// Auto handles the auto subcommand, inferring the bump type from the
+// conventional commits since the latest tag.
+func (a App) Auto(ctx context.Context, options BumpOptions) error {
+	if err := a.ensureRepo(ctx); err != nil {
+		return err
 	}
 
-	dirty, err := git.IsDirty()
+	commits, err := a.commitsSinceLatest(ctx)
 	if err != nil {
 		return err
 	}
 
-	if err = a.runHook(hooks.StagePreCommit, dryRun); err != nil {
+	typ, reasons, err := inferBumpType(commits)
+	if err != nil {
 		return err
 	}
 
-	// Only any point in committing if something has changed
-	if dirty {
-		if dryRun {
-			msg.Finfo(a.Stdout, "(Dry Run) Would commit changes")
-			return nil
-		}
-		msg.Finfo(a.Stdout, "Committing changes")
-		if err = git.Add(); err != nil {
-			return err
-		}
-
-		commitOut, err := git.Commit(a.Cfg.Git.MessageTemplate)
-		if err != nil {
-			return errors.New(commitOut)
+	msg.Finfo(a.Stdout, "Detected a %s bump from %d commit(s)", typ, len(reasons))
+	if options.DryRun {
+		for _, reason := range reasons {
+			fmt.Fprintf(a.Stdout, "  %.7s %s\n", reason.Hash, reason.Subject())
 		}
 	}
-	return nil
+
+	return a.bump(ctx, typ, options)
 }
 
-// replace is a helper that performs file replacement.
-func (a App) replace(dryRun bool) error {
-	for _, file := range a.Cfg.Files {
-		var contents []byte
-		contents, err := os.ReadFile(file.Path)
-		if err != nil {
-			return err
-		}
+// Next handles the next subcommand, printing the version a bump of the given type
+// would produce without changing anything.
+func (a App) Next(ctx context.Context, bump string, options NextOptions) error {
+	var typ bumpType
+	switch bump {
+	case "major":
+		typ = major
+	case "minor":
+		typ = minor
+	case "patch":
+		typ = patch
+	case "pre":
+		typ = pre
+	case "release":
+		typ = release
+	default:
+		return fmt.Errorf("unrecognised bump type %q, must be one of major, minor, patch, pre or release", bump)
+	}
 
-		if !bytes.Contains(contents, []byte(file.Search)) {
-			return fmt.Errorf("could not find %q in %s", file.Search, file.Path)
-		}
+	if err := a.ensureRepo(ctx); err != nil {
+		return err
+	}
 
-		if dryRun {
-			msg.Finfo(a.Stdout, "(Dry Run) Would replace %s with %s in %s", file.Search, file.Replace, file.Path)
-		} else {
-			msg.Finfo(a.Stdout, "Replacing contents in %s", file.Path)
-			newContent := bytes.ReplaceAll(contents, []byte(file.Search), []byte(file.Replace))
+	format := options.Format
+	if format == "" {
+		format = "{{.Next}}"
+	}
 
-			if err = os.WriteFile(file.Path, newContent, filePermissions); err != nil {
-				return err
-			}
-		}
+	tmp, err := template.New("format").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return fmt.Errorf("could not parse --format: %w", err)
 	}
-	return nil
-}
 
-// getBumpVersions is a helper that gets .Current and .Next from context.
-func (a App) getBumpVersions(typ bumpType) (current, next semver.Version, err error) {
-	if a.replaceMode {
-		// If the config file is present, use the version specified in there
-		current, err = semver.Parse(a.Cfg.Version)
-		if err != nil {
-			return semver.Version{}, semver.Version{}, err
-		}
-	} else {
-		// Otherwise start at the latest semver tag present
-		latest, err := git.LatestTag()
-		if err != nil {
-			if !errors.Is(err, git.ErrNoTagsFound) {
-				return semver.Version{}, semver.Version{}, err
-			}
+	current, next, err := a.getBumpVersions(ctx, typ, options.Pre)
+	if err != nil {
+		return err
+	}
 
-			current = semver.Version{} // No tags, no default version, start at v0.0.0
-		} else {
-			current, err = semver.Parse(latest)
-			if err != nil {
-				return semver.Version{}, semver.Version{}, err
-			}
-		}
+	data := struct {
+		Tag           string
+		Current, Next semver.Version
+	}{
+		Current: current,
+		Next:    next,
+		Tag:     a.tagPrefix + next.Tag(),
 	}
 
-	switch typ {
-	case major:
-		next = semver.BumpMajor(current)
-	case minor:
-		next = semver.BumpMinor(current)
-	case patch:
-		next = semver.BumpPatch(current)
-	default:
-		return semver.Version{}, semver.Version{}, fmt.Errorf("unrecognised bump type: %v", typ)
+	buf := &bytes.Buffer{}
+	if err := tmp.Execute(buf, data); err != nil {
+		return fmt.Errorf("could not execute --format: %w", err)
 	}
 
-	return current, next, nil
+	fmt.Fprintln(a.Stdout, strings.TrimSuffix(buf.String(), "\n"))
+	return nil
 }
 
-// bump is a helper that performs logic common to all bump methods.
-func (a App) bump(typ bumpType, push, force, dryRun bool) error {
-	if err := a.ensureRepo(); err != nil {
+// Undo handles the undo subcommand, rolling back the most recent bump by deleting
+// its tag and, if there was one, resetting the bump commit.
+func (a App) Undo(ctx context.Context, options UndoOptions) error {
+	if err := a.ensureRepo(ctx); err != nil {
 		return err
 	}
-	if err := a.ensureBumpable(); err != nil {
+	if err := a.ensureWritable("undoing"); err != nil {
 		return err
 	}
-
-	current, next, err := a.getBumpVersions(typ)
-	if err != nil {
+	if err := a.ensureBumpable(ctx); err != nil {
 		return err
 	}
 
-	if !force {
-		confirm := huh.NewConfirm().Inline(true).Title(fmt.Sprintf("This will bump %q to %q. Are you sure?", current, next)).Value(&force)
-		if err := confirm.Run(); err != nil {
-			return err
+	tag, err := a.Repo.LatestTag(ctx, a.tagPrefix)
+	if err != nil {
+		if errors.Is(err, git.ErrNoTagsFound) {
+			return fmt.Errorf("nothing to undo: %w", err)
 		}
+		return err
 	}
 
-	// Now if force is false, the user said no -> abort
-	if !force {
-		return ErrAborted
+	version, err := semver.Parse(strings.TrimPrefix(tag, a.tagPrefix))
+	if err != nil {
+		return fmt.Errorf("latest tag %s is not a semver tag: %w", tag, err)
 	}
 
-	if err := a.runHook(hooks.StagePreReplace, dryRun); err != nil {
+	tagged, err := a.Repo.RevParse(ctx, tag)
+	if err != nil {
+		return err
+	}
+	head, err := a.Repo.RevParse(ctx, "HEAD")
+	if err != nil {
 		return err
 	}
+	if tagged != head {
+		return fmt.Errorf("latest tag %s is not on HEAD, there have been commits since the bump", tag)
+	}
 
+	previous := ""
 	if a.replaceMode {
-		if err := a.replaceAll(current, next, dryRun); err != nil {
+		previous, err = a.checkBumpCommit(ctx, version)
+		if err != nil {
 			return err
 		}
 	}
 
-	if err := a.runHook(hooks.StagePreTag, dryRun); err != nil {
+	remotes, err := a.remotes(ctx, "")
+	if err != nil {
 		return err
 	}
-
-	if dryRun {
-		msg.Finfo(a.Stdout, "(Dry Run) Would issue new tag %s", next.Tag())
-	} else {
-		msg.Finfo(a.Stdout, "Issuing new tag %s", next.Tag())
-		stdout, err := git.CreateTag(next.Tag(), a.Cfg.Git.TagTemplate)
+	var pushedTo []string
+	for _, remote := range remotes {
+		pushed, err := a.Repo.RemoteHasTag(ctx, remote, tag)
 		if err != nil {
-			return errors.New(stdout)
+			return err
+		}
+		if pushed {
+			pushedTo = append(pushedTo, remote)
 		}
 	}
+	pushed := len(pushedTo) != 0
+	if pushed && !options.Remote {
+		return fmt.Errorf("tag %s has already been pushed to %s, pass --remote to delete it there too", tag, strings.Join(pushedTo, ", "))
+	}
 
-	// If --push, push the tag and commit
-	if push {
-		if err := a.runHook(hooks.StagePrePush, dryRun); err != nil {
+	force, dryRun := options.Force, options.DryRun
+	if !force {
+		confirm := huh.NewConfirm().Inline(true).Title(fmt.Sprintf("This will undo the bump to %q. Are you sure?", tag)).Value(&force)
+		if err := confirm.Run(); err != nil {
 			return err
 		}
+	}
+
+	if !force {
+		return ErrAborted
+	}
+
+	for _, remote := range pushedTo {
 		if dryRun {
-			msg.Finfo(a.Stdout, "(Dry Run) Would push tag %s", next.Tag())
-			return nil
+			msg.Finfo(a.Stdout, "(Dry Run) Would delete tag %s from %s", tag, remote)
+		} else {
+			msg.Finfo(a.Stdout, "Deleting tag %s from %s", tag, remote)
+			stdout, err := a.Repo.DeleteRemoteTag(ctx, remote, tag)
+			if err != nil {
+				return errors.New(stdout)
+			}
 		}
-		msg.Finfo(a.Stdout, "Pushing tag %s", next.Tag())
-		stdout, err := git.Push()
+	}
+
+	if dryRun {
+		msg.Finfo(a.Stdout, "(Dry Run) Would delete tag %s", tag)
+	} else {
+		msg.Finfo(a.Stdout, "Deleting tag %s", tag)
+		stdout, err := a.Repo.DeleteTag(ctx, tag)
 		if err != nil {
 			return errors.New(stdout)
 		}
 	}
+
+	if a.replaceMode {
+		// Resetting puts every file back, including the version in the config file
+		if dryRun {
+			msg.Finfo(a.Stdout, "(Dry Run) Would reset the bump commit, restoring version %s", previous)
+		} else {
+			msg.Finfo(a.Stdout, "Resetting the bump commit, restoring version %s", previous)
+			stdout, err := a.Repo.Reset(ctx, head+"~1")
+			if err != nil {
+				return errors.New(stdout)
+			}
+		}
+	}
+
+	if pushed && a.replaceMode && a.Cfg.Git.PushMode != config.PushModeTag {
+		msg.Fwarn(a.Stdout, "The bump commit was pushed to %s along with the tag, you will need to revert it there", strings.Join(pushedTo, ", "))
+	}
+
 	return nil
 }
 
-// runHook is a helper that runs a particular hook stage (if it is defined)
-// and understands --dry-run.
-func (a App) runHook(stage hooks.HookStage, dryRun bool) error {
-	var hookCmd string
+// checkBumpCommit is a helper that makes sure HEAD is the commit tag made when bumping
+// to version, returning the version it was bumped from.
+func (a App) checkBumpCommit(ctx context.Context, version semver.Version) (string, error) {
+	if a.Cfg.Version != version.String() {
+		return "", fmt.Errorf("version in %s is %s, not %s, HEAD is not a bump commit", a.configFile, a.Cfg.Version, version)
+	}
+
+	raw, err := a.Repo.Show(ctx, "HEAD~1", a.configFile)
+	if err != nil {
+		return "", err
+	}
+	previous, err := keypath.Get(keypath.TOML, raw, a.versionKey)
+	if err != nil {
+		return "", fmt.Errorf("could not get the version before HEAD from %s: %w", a.configFile, err)
+	}
+
+	// Render a copy so a.Cfg still has the raw templates. What the hooks captured
+	// at the time isn't known, so each capture is a placeholder that matches anything
+	placeholders := make(map[string]string)
+	for _, name := range a.Cfg.Hooks.Captures(hooks.StagePreTag) {
+		placeholders[name] = "\x00" + name + "\x00"
+	}
+	rendered := a.Cfg
+	if err := rendered.Render(previous, version.String(), placeholders); err != nil {
+		return "", err
+	}
+	pattern := regexp.QuoteMeta(strings.TrimSpace(rendered.Git.MessageTemplate))
+	for _, placeholder := range placeholders {
+		pattern = strings.ReplaceAll(pattern, placeholder, "(?s:.*)")
+	}
+
+	message, err := a.Repo.CommitMessage(ctx, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if !regexp.MustCompile("^" + pattern + "$").MatchString(message) {
+		return "", fmt.Errorf("HEAD is not the bump commit from %s to %s, its message is %q", previous, version, message)
+	}
+
+	return previous, nil
+}
+
+// commitsSinceLatest is a helper that returns the commits since the latest tag
+// or the entire history if there are no tags yet.
+func (a App) commitsSinceLatest(ctx context.Context) ([]git.LogEntry, error) {
+	since, err := a.Repo.LatestTag(ctx, a.tagPrefix)
+	if err != nil {
+		if !errors.Is(err, git.ErrNoTagsFound) {
+			return nil, err
+		}
+		since = "" // No tags yet, consider the whole history
+	}
+
+	if a.modulePath != "" {
+		// Only the commits touching the module count towards its next version
+		return a.Repo.CommitsSince(ctx, since, a.modulePath)
+	}
+
+	return a.Repo.CommitsSince(ctx, since)
+}
+
+// inferBumpType applies the conventional commits rules to commits to decide
+// which bump they warrant, it also returns the commits that drove the decision.
+func inferBumpType(commits []git.LogEntry) (bumpType, []git.LogEntry, error) {
+	var breaking, features, fixes []git.LogEntry
+	for _, commit := range commits {
+		parsed, err := conventional.Parse(commit.Message)
+		if err != nil {
+			// Non conventional commits don't count towards anything
+			continue
+		}
+		switch {
+		case parsed.Breaking:
+			breaking = append(breaking, commit)
+		case parsed.IsFeature():
+			features = append(features, commit)
+		case parsed.IsFix():
+			fixes = append(fixes, commit)
+		}
+	}
+
+	switch {
+	case len(breaking) != 0:
+		return major, breaking, nil
+	case len(features) != 0:
+		return minor, features, nil
+	case len(fixes) != 0:
+		return patch, fixes, nil
+	default:
+		return 0, nil, fmt.Errorf("%w: found %d commit(s) but no feat, fix or breaking changes", ErrNothingToRelease, len(commits))
+	}
+}
+
+// replaceAll is a helper that performs and reports on file replacement
+// as part of bumping, a.Cfg.Files must already be rendered.
+func (a App) replaceAll(ctx context.Context, current, next semver.Version, dryRun bool, tx *transaction) error {
+	if err := a.replace(ctx, dryRun, tx); err != nil {
+		return err
+	}
+
+	if err := a.rewriteGoModule(next, dryRun, tx); err != nil {
+		return err
+	}
+
+	if err := a.writeChangelog(ctx, current, next, dryRun, tx); err != nil {
+		return err
+	}
+
+	// Also replace the Version in the config file, editing just that key so
+	// any comments and formatting the user has in there survive
+	if !dryRun {
+		if err := tx.snapshot(a.configFile); err != nil {
+			return err
+		}
+		if err := config.SetVersion(a.path(a.configFile), a.versionKey, next.String()); err != nil {
+			return err
+		}
+	}
+	a.result.file(a.configFile, 1)
+
+	dirty, err := a.Repo.IsDirty(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = a.runHook(ctx, hooks.StagePreCommit, dryRun); err != nil {
+		return err
+	}
+
+	// Only any point in committing if something has changed
+	if dirty {
+		if dryRun {
+			msg.Finfo(a.Stdout, "(Dry Run) Would commit changes")
+			return nil
+		}
+		msg.Finfo(a.Stdout, "Committing changes")
+		head, err := a.Repo.RevParse(ctx, "HEAD")
+		if err != nil {
+			return atStage("commit", err)
+		}
+		if err = tx.stage(ctx, head); err != nil {
+			return atStage("commit", err)
+		}
+
+		messages, err := a.messages(current, next)
+		if err != nil {
+			return atStage("commit", err)
+		}
+		var commitOut string
+		if a.Cfg.Git.SignCommits {
+			commitOut, err = a.Repo.SignedCommit(ctx, messages.Git.MessageTemplate, a.signing())
+		} else {
+			commitOut, err = a.Repo.Commit(ctx, messages.Git.MessageTemplate)
+		}
+		if err != nil {
+			return atStage("commit", errors.New(commitOut))
+		}
+		tx.committed()
+
+		if a.result != nil {
+			a.result.Commit, err = a.Repo.RevParse(ctx, "HEAD")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replace is a helper that performs file replacement.
+//
+// A [[file]] path may be a glob, in which case every matching file is replaced
+// and it's only an error if none of them contained the search string.
+func (a App) replace(ctx context.Context, dryRun bool, tx *transaction) error {
+	for _, file := range a.Cfg.Files {
+		paths, err := a.expandPaths(ctx, file.Path)
+		if err != nil {
+			return err
+		}
+
+		total := 0
+		for _, path := range paths {
+			single := file
+			single.Path = path
+
+			contents, err := os.ReadFile(a.path(path))
+			if err != nil {
+				return err
+			}
+
+			newContent, count, err := replaceContents(single, contents)
+			if err != nil {
+				return err
+			}
+
+			if count == 0 {
+				if !isGlob(file.Path) {
+					return fmt.Errorf("could not find %q in %s", file.Search, path)
+				}
+				continue
+			}
+			total += count
+
+			a.result.file(path, count)
+			switch {
+			case dryRun && file.Format != "":
+				msg.Finfo(a.Stdout, "(Dry Run) Would set %s from %s to %s in %s", file.Key, file.Search, file.Replace, path)
+			case dryRun:
+				msg.Finfo(a.Stdout, "(Dry Run) Would replace %d occurrence(s) of %s with %s in %s", count, file.Search, file.Replace, path)
+			default:
+				msg.Finfo(a.Stdout, "Replacing %d occurrence(s) in %s", count, path)
+				if err = tx.write(path, newContent); err != nil {
+					return err
+				}
+			}
+		}
+
+		if total == 0 {
+			return fmt.Errorf("could not find %q in any of the %d file(s) matching %s", file.Search, len(paths), file.Path)
+		}
+	}
+	return nil
+}
+
+// replaceContents is a helper that performs the search and replace for a single
+// (rendered) file on its contents, returning the new contents and the number of
+// replacements made.
+func replaceContents(file config.File, contents []byte) ([]byte, int, error) {
+	if file.Format != "" {
+		value, err := keypath.Get(file.Format, contents, file.Key)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not read %s from %s: %w", file.Key, file.Path, err)
+		}
+
+		if value != file.Search {
+			return nil, 0, fmt.Errorf("%s in %s is %q, expected %q", file.Key, file.Path, value, file.Search)
+		}
+
+		updated, err := keypath.Set(file.Format, contents, file.Key, file.Replace)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not write %s to %s: %w", file.Key, file.Path, err)
+		}
+
+		return updated, 1, nil
+	}
+
+	if !file.Regex {
+		count := bytes.Count(contents, []byte(file.Search))
+		return bytes.ReplaceAll(contents, []byte(file.Search), []byte(file.Replace)), count, nil
+	}
+
+	re, err := regexp.Compile(file.Search)
+	if err != nil {
+		return nil, 0, fmt.Errorf("file.search for file %s is not a valid regex: %w", file.Path, err)
+	}
+
+	count := len(re.FindAllIndex(contents, -1))
+	return re.ReplaceAll(contents, []byte(file.Replace)), count, nil
+}
+
+// rewriteGoModule is a helper that, if enabled, moves the Go module to the
+// major version of next, rewriting go.mod and the module's own imports.
+func (a App) rewriteGoModule(next semver.Version, dryRun bool, tx *transaction) error {
+	if !a.Cfg.Go.RewriteModulePath {
+		return nil
+	}
+
+	dir := a.Cfg.Go.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	plan, err := gomod.Rewrite(a.path(dir), next.Major)
+	if err != nil {
+		return err
+	}
+
+	if plan.From == plan.To {
+		// Same major version, or still on v0/v1
+		return nil
+	}
+
+	if dryRun {
+		msg.Finfo(a.Stdout, "(Dry Run) Would change module path %s to %s", plan.From, plan.To)
+	} else {
+		msg.Finfo(a.Stdout, "Changing module path %s to %s", plan.From, plan.To)
+	}
+
+	for _, change := range plan.Changes {
+		// Report, and write, the paths relative to the config file like any others
+		path, err := filepath.Rel(a.dir, change.Path)
+		if err != nil {
+			return err
+		}
+		a.result.file(path, change.Count)
+		if dryRun {
+			msg.Finfo(a.Stdout, "(Dry Run) Would rewrite %d module path(s) in %s", change.Count, path)
+			continue
+		}
+		msg.Finfo(a.Stdout, "Rewriting %d module path(s) in %s", change.Count, path)
+		if err := tx.write(path, change.Contents); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// writeChangelog is a helper that updates the configured changelog file, either by
+// generating a new section from the commits since the latest tag, or by promoting
+// the Unreleased section of a Keep a Changelog style file.
+func (a App) writeChangelog(ctx context.Context, current, next semver.Version, dryRun bool, tx *transaction) error {
+	path := a.Cfg.Changelog.Path
+	if path == "" {
+		// Changelog not configured
+		return nil
+	}
+
+	contents, err := os.ReadFile(a.path(path))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	date := time.Now().Format(time.DateOnly)
+
+	var updated []byte
+	switch a.Cfg.Changelog.Style {
+	case "", changelog.StyleGenerate:
+		commits, err := a.commitsSinceLatest(ctx)
+		if err != nil {
+			return err
+		}
+
+		section := changelog.Section{
+			Version:  next.String(),
+			Previous: current.String(),
+			Tag:      a.tagPrefix + next.Tag(),
+			Date:     date,
+			Groups:   changelog.GroupCommits(commits),
+		}
+
+		rendered, err := changelog.Render(a.Cfg.Changelog.Template, section)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			msg.Finfo(a.Stdout, "(Dry Run) Would add the following to %s", path)
+			fmt.Fprintln(a.Stdout, strings.TrimSpace(rendered))
+			return nil
+		}
+
+		updated = changelog.Prepend(contents, rendered)
+	case changelog.StyleKeepAChangelog:
+		updated, err = changelog.Promote(contents, next.String(), a.tagPrefix+next.Tag(), date)
+		if err != nil {
+			return fmt.Errorf("could not update %s: %w", path, err)
+		}
+
+		if dryRun {
+			msg.Finfo(a.Stdout, "(Dry Run) Would move Unreleased to [%s] - %s in %s", next, date, path)
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown changelog style %q, expected %q or %q", a.Cfg.Changelog.Style, changelog.StyleGenerate, changelog.StyleKeepAChangelog)
+	}
+
+	msg.Finfo(a.Stdout, "Updating changelog %s", path)
+	a.result.file(path, 1)
+	return tx.write(path, updated)
+}
+
+// getBumpVersions is a helper that gets .Current and .Next from context.
+//
+// If label is not empty, next will be a pre-release with that label and the
+// next available counter e.g. "1.3.0-rc.2".
+func (a App) getBumpVersions(ctx context.Context, typ bumpType, label string) (current, next semver.Version, err error) {
+	if a.replaceMode {
+		// If the config file is present, use the version specified in there
+		current, err = semver.Parse(a.Cfg.Version)
+		if err != nil {
+			return semver.Version{}, semver.Version{}, err
+		}
+	} else {
+		// Otherwise start at the latest semver tag present
+		latest, err := a.Repo.LatestTag(ctx, a.tagPrefix)
+		if err != nil {
+			if !errors.Is(err, git.ErrNoTagsFound) {
+				return semver.Version{}, semver.Version{}, err
+			}
+
+			current = semver.Version{} // No tags, no default version, start at v0.0.0
+		} else {
+			current, err = semver.Parse(strings.TrimPrefix(latest, a.tagPrefix))
+			if err != nil {
+				return semver.Version{}, semver.Version{}, err
+			}
+		}
+	}
+
+	switch typ {
+	case major:
+		next = semver.BumpMajor(current)
+	case minor:
+		next = semver.BumpMinor(current)
+	case patch:
+		next = semver.BumpPatch(current)
+	case pre:
+		if current.Prerelease == "" {
+			return semver.Version{}, semver.Version{}, fmt.Errorf(
+				"current version %s is not a pre-release, start one with e.g. 'tag minor --pre rc'",
+				current,
+			)
+		}
+		if label == "" {
+			// Carry on with the current label
+			label, _ = splitPrerelease(current.Prerelease)
+		}
+		next = semver.Version{Major: current.Major, Minor: current.Minor, Patch: current.Patch}
+	case release:
+		if current.Prerelease == "" {
+			return semver.Version{}, semver.Version{}, fmt.Errorf("current version %s is not a pre-release, nothing to release", current)
+		}
+		label = "" // A release is never a pre-release
+		next = semver.Version{Major: current.Major, Minor: current.Minor, Patch: current.Patch}
+	default:
+		return semver.Version{}, semver.Version{}, fmt.Errorf("unrecognised bump type: %v", typ)
+	}
+
+	if label != "" {
+		counter, err := a.nextPrerelease(ctx, current, next, label)
+		if err != nil {
+			return semver.Version{}, semver.Version{}, err
+		}
+		next.Prerelease = fmt.Sprintf("%s.%d", label, counter)
+	}
+
+	if typ == pre && comparePrerelease(next.Prerelease, current.Prerelease) <= 0 {
+		// A label that sorts lower (e.g. rc -> beta) would tag a version older than the current one
+		return semver.Version{}, semver.Version{}, fmt.Errorf(
+			"next version %s would not be greater than the current version %s, a pre-release label can only move forward e.g. alpha -> beta -> rc",
+			next, current,
+		)
+	}
+
+	return current, next, nil
+}
+
+// nextPrerelease works out the next pre-release counter for label on top of the
+// version base, by looking at the current version and all the existing tags.
+//
+// So if there are tags v1.3.0-rc.1 and v1.3.0-rc.2, the next "rc" counter for
+// base 1.3.0 is 3.
+func (a App) nextPrerelease(ctx context.Context, current, base semver.Version, label string) (int, error) {
+	if !prereleaseLabel.MatchString(label) {
+		return 0, fmt.Errorf("invalid pre-release label %q, must only contain alphanumerics and hyphens", label)
+	}
+
+	candidates := []semver.Version{current}
+
+	tags, _, err := a.Repo.ListTags(ctx, a.tagPrefix, math.MaxInt)
+	if err != nil && !errors.Is(err, git.ErrNoTagsFound) {
+		return 0, err
+	}
+
+	for line := range strings.Lines(tags) {
+		version, err := semver.Parse(strings.TrimPrefix(strings.TrimSpace(line), a.tagPrefix))
+		if err != nil {
+			// Not a semver tag, not our concern
+			continue
+		}
+		candidates = append(candidates, version)
+	}
+
+	highest := 0
+	for _, candidate := range candidates {
+		if candidate.Major != base.Major || candidate.Minor != base.Minor || candidate.Patch != base.Patch {
+			continue
+		}
+		candidateLabel, counter := splitPrerelease(candidate.Prerelease)
+		if candidateLabel == label && counter > highest {
+			highest = counter
+		}
+	}
+
+	return highest + 1, nil
+}
+
+// splitPrerelease splits a pre-release string like "rc.4" into it's label
+// and counter, if there is no numeric counter it is returned as 0.
+func splitPrerelease(prerelease string) (label string, counter int) {
+	label, number, ok := strings.Cut(prerelease, ".")
+	if !ok {
+		return label, 0
+	}
+	counter, err := strconv.Atoi(number)
+	if err != nil {
+		return label, 0
+	}
+	return label, counter
+}
+
+// comparePrerelease compares two pre-release strings of the same version by semver
+// precedence, returning -1, 0 or 1 as a is lower than, equal to or higher than b.
+//
+// Dot separated identifiers are compared in turn, numerically if both are numbers
+// and a number is always lower than a word, and if one runs out first it's lower.
+func comparePrerelease(a, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(left), len(right)) {
+		leftNum, leftErr := strconv.ParseUint(left[i], 10, 64)
+		rightNum, rightErr := strconv.ParseUint(right[i], 10, 64)
+		switch {
+		case leftErr == nil && rightErr == nil:
+			if c := cmp.Compare(leftNum, rightNum); c != 0 {
+				return c
+			}
+		case leftErr == nil:
+			return -1
+		case rightErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(left), len(right))
+}
+
+// bump is a helper that performs logic common to all bump methods.
+func (a App) bump(ctx context.Context, typ bumpType, options BumpOptions) error {
+	if err := a.ensureRepo(ctx); err != nil {
+		return err
+	}
+	if err := a.ensureWritable("bumping"); err != nil {
+		return err
+	}
+	if err := a.ensureFiles(); err != nil {
+		return err
+	}
+	if err := a.ensureBumpable(ctx); err != nil {
+		return err
+	}
+	if err := a.ensureChangelog(options.AllowEmptyChangelog); err != nil {
+		return err
+	}
+
+	current, next, err := a.getBumpVersions(ctx, typ, options.Pre)
+	if err != nil {
+		return err
+	}
+
+	force := options.Force
+	if !force {
+		confirm := huh.NewConfirm().Inline(true).Title(fmt.Sprintf("This will bump %q to %q. Are you sure?", current, next)).Value(&force)
+		if err := confirm.Run(); err != nil {
+			return err
+		}
+	}
+
+	// Now if force is false, the user said no -> abort
+	if !force {
+		return ErrAborted
+	}
+
+	a.result = &bumpResult{
+		Current: current.String(),
+		Next:    next.String(),
+		Tag:     a.tagPrefix + next.Tag(),
+		Files:   []fileJSON{},
+		Hooks:   []string{},
+		DryRun:  options.DryRun,
+	}
+
+	// If the bump is interrupted, cleaning up mustn't be
+	cleanup := context.WithoutCancel(ctx)
+
+	tx := newTransaction(a.Repo, a.dir)
+	if err := a.apply(ctx, current, next, options, tx); err != nil {
+		interrupted := ctx.Err() != nil
+		if interrupted {
+			err = a.interrupted(ctx, err)
+		}
+
+		var rollbackErr error
+		if !tx.empty() {
+			msg.Fwarn(a.Stdout, "Bump failed, reverting changes")
+			if rollbackErr = tx.rollback(cleanup, a.Stdout); rollbackErr != nil {
+				err = errors.Join(err, fmt.Errorf("could not revert all changes: %w", rollbackErr))
+			}
+		}
+
+		if interrupted {
+			switch {
+			case tx.empty():
+				msg.Finfo(a.Stdout, "Nothing had been changed yet, the repo is as it was")
+			case rollbackErr == nil:
+				msg.Finfo(a.Stdout, "Everything was put back, the repo is as it was before the bump")
+			default:
+				msg.Ferror(a.Stdout, "The repo was left part way through the bump, see below for what could not be put back")
+			}
+		}
+		return a.onError(cleanup, err, options.DryRun)
+	}
+
+	if options.Push {
+		// The tag is on the remote now so there's no undoing it, a failure is just reported
+		if err := a.runHook(ctx, hooks.StagePostPush, options.DryRun); err != nil {
+			if ctx.Err() != nil {
+				err = a.interrupted(ctx, err)
+				msg.Finfo(a.Stdout, "The tag was already pushed so nothing was undone")
+			}
+			return a.onError(cleanup, err, options.DryRun)
+		}
+	}
+
+	if a.jsonOut != nil {
+		return a.writeJSON(a.result)
+	}
+
+	return nil
+}
+
+// apply is a helper that performs the steps of a bump once it's been confirmed,
+// recording everything it changes in tx so it can be undone if a later step fails.
+func (a App) apply(ctx context.Context, current, next semver.Version, options BumpOptions, tx *transaction) error {
+	// Render everything up front so any problems are found before changing anything,
+	// but only keep the files, the commit and tag messages are rendered when they're
+	// needed so they have the output captured by the hooks that ran before
+	rendered := a.Cfg
+	if err := rendered.Render(current.String(), next.String(), a.captured()); err != nil {
+		return atStage("replace", err)
+	}
+	a.Cfg.Files = rendered.Files
+
+	dryRun := options.DryRun
+	if err := a.runHook(ctx, hooks.StagePreReplace, dryRun); err != nil {
+		return err
+	}
+
+	if a.replaceMode {
+		if err := a.replaceAll(ctx, current, next, dryRun, tx); err != nil {
+			return atStage("replace", err)
+		}
+	}
+
+	if err := a.runHook(ctx, hooks.StagePreTag, dryRun); err != nil {
+		return err
+	}
+
+	tag := a.tagPrefix + next.Tag()
+	kind := "tag"
+	if a.Cfg.Git.SignTags {
+		kind = "signed tag"
+	}
+	if dryRun {
+		msg.Finfo(a.Stdout, "(Dry Run) Would issue new %s %s", kind, tag)
+	} else {
+		msg.Finfo(a.Stdout, "Issuing new %s %s", kind, tag)
+		messages, err := a.messages(current, next)
+		if err != nil {
+			return atStage("tag", err)
+		}
+		var stdout string
+		if a.Cfg.Git.SignTags {
+			stdout, err = a.Repo.SignedTag(ctx, tag, messages.Git.TagTemplate, a.signing())
+		} else {
+			stdout, err = a.Repo.CreateTag(ctx, tag, messages.Git.TagTemplate)
+		}
+		if err != nil {
+			return atStage("tag", errors.New(stdout))
+		}
+		tx.tagged(tag)
+	}
+
+	if err := a.runHook(ctx, hooks.StagePostTag, dryRun); err != nil {
+		return err
+	}
+
+	// If --push, push the tag (and branch) to each remote
+	if options.Push {
+		if err := a.runHook(ctx, hooks.StagePrePush, dryRun); err != nil {
+			return err
+		}
+		remotes, err := a.remotes(ctx, options.Remote)
+		if err != nil {
+			return atStage("push", err)
+		}
+		if len(remotes) == 0 {
+			return atStage("push", errors.New("no remote to push to, add one with git remote add or set git.remote"))
+		}
+		refs, err := a.pushRefs(ctx, tag)
+		if err != nil {
+			return atStage("push", err)
+		}
+
+		for i, remote := range remotes {
+			if dryRun {
+				msg.Finfo(a.Stdout, "(Dry Run) Would push %s to %s", describeRefs(refs), remote)
+				continue
+			}
+			msg.Finfo(a.Stdout, "Pushing %s to %s", describeRefs(refs), remote)
+			stdout, err := a.Repo.Push(ctx, remote, refs...)
+			if err != nil {
+				if i > 0 {
+					msg.Fwarn(a.Stdout, "Already pushed to %s, that will need undoing there", strings.Join(remotes[:i], ", "))
+				}
+				return atStage("push", fmt.Errorf("could not push to %s: %s", remote, strings.TrimSpace(stdout)))
+			}
+		}
+		a.result.Pushed = true
+		a.result.Remotes = remotes
+	}
+	return nil
+}
+
+// remotes returns the remotes to push to, override if given, else those set in the
+// config file, else the one git push would use. It's empty if there are no remotes.
+func (a App) remotes(ctx context.Context, override string) ([]string, error) {
+	switch {
+	case override != "":
+		return []string{override}, nil
+	case len(a.Cfg.Git.PushRemotes) != 0:
+		return a.Cfg.Git.PushRemotes, nil
+	case a.Cfg.Git.Remote != "":
+		return []string{a.Cfg.Git.Remote}, nil
+	}
+
+	remote, err := a.Repo.DefaultRemote(ctx)
+	if err != nil || remote == "" {
+		return nil, err
+	}
+	return []string{remote}, nil
+}
+
+// pushRefs returns the refs to push for tag, according to git.push-mode.
+//
+// Everything is named explicitly rather than using --follow-tags, which skips
+// lightweight tags and pushes any other annotated tags reachable from the branch.
+func (a App) pushRefs(ctx context.Context, tag string) ([]string, error) {
+	refs := []string{"refs/tags/" + tag}
+	if a.Cfg.Git.PushMode == config.PushModeTag {
+		return refs, nil
+	}
+
+	branch, err := a.Repo.Branch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if branch == "HEAD" {
+		return nil, errors.New("cannot push the branch from a detached HEAD, set git.push-mode to \"tag\" to push only the tag")
+	}
+	return append([]string{"refs/heads/" + branch}, refs...), nil
+}
+
+// describeRefs is a helper that describes refs being pushed for humans
+// e.g. "branch main and tag v1.2.3".
+func describeRefs(refs []string) string {
+	described := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+			described = append(described, "branch "+branch)
+		} else {
+			described = append(described, "tag "+strings.TrimPrefix(ref, "refs/tags/"))
+		}
+	}
+	return strings.Join(described, " and ")
+}
+
+// runHook is a helper that runs a particular hook stage (if it is defined)
+// and understands --dry-run.
+func (a App) runHook(ctx context.Context, stage hooks.HookStage, dryRun bool) error {
+	var hook config.Hook
 	switch stage {
 	case hooks.StagePreReplace:
-		hookCmd = a.Cfg.Hooks.PreReplace
+		hook = a.Cfg.Hooks.PreReplace
 	case hooks.StagePreCommit:
-		hookCmd = a.Cfg.Hooks.PreCommit
+		hook = a.Cfg.Hooks.PreCommit
 	case hooks.StagePreTag:
-		hookCmd = a.Cfg.Hooks.PreTag
+		hook = a.Cfg.Hooks.PreTag
+	case hooks.StagePostTag:
+		hook = a.Cfg.Hooks.PostTag
 	case hooks.StagePrePush:
-		hookCmd = a.Cfg.Hooks.PrePush
+		hook = a.Cfg.Hooks.PrePush
+	case hooks.StagePostPush:
+		hook = a.Cfg.Hooks.PostPush
 	default:
 		return fmt.Errorf("unhandled hook type: %s", stage)
 	}
 
-	if hookCmd == "" {
+	if err := a.execHook(ctx, stage, hook, a.hookVars(), dryRun); err != nil {
+		return atStage(stage.Key(), err)
+	}
+	return nil
+}
+
+// onError is a helper that runs the on-error hook (if it is defined) for err, which
+// stopped the bump, returning err along with any error from the hook itself.
+func (a App) onError(ctx context.Context, err error, dryRun bool) error {
+	vars := a.hookVars()
+	vars.FailedStage = failedStage(err)
+	vars.Error = err.Error()
+
+	if hookErr := a.execHook(ctx, hooks.StageOnError, a.Cfg.Hooks.OnError, vars, dryRun); hookErr != nil {
+		return errors.Join(err, hookErr)
+	}
+	return err
+}
+
+// execHook is a helper that runs the commands of a hook stage with vars, a no-op
+// if there aren't any, in a dry run they're only printed.
+//
+// If the hook has more than one command, or a failure didn't stop it, each command
+// is reported as passed or failed once it's finished.
+func (a App) execHook(ctx context.Context, stage hooks.HookStage, hook config.Hook, vars hooks.Vars, dryRun bool) error {
+	if len(hook.Commands) == 0 {
 		// No op if the hook is not defined
 		return nil
 	}
 
+	commands, err := hook.Parse()
+	if err != nil {
+		return err
+	}
+
+	a.result.hook(stage)
+
 	if dryRun {
-		msg.Finfo(a.Stdout, "(Dry Run) Would run hook %s: %s", stage, hookCmd)
+		for _, command := range commands {
+			rendered, err := hooks.Render(stage, command.Run, vars)
+			if err != nil {
+				return err
+			}
+			msg.Finfo(a.Stdout, "(Dry Run) Would run hook %s: %s", stage, rendered)
+		}
 		return nil
 	}
-	return hooks.Run(stage, hookCmd, a.Stdout, a.Stderr)
+
+	for i := range commands {
+		commands[i].Dir = a.path(commands[i].Dir)
+	}
+
+	results, err := hooks.Run(ctx, stage, commands, vars, a.Stdout, a.Stderr)
+	for i, result := range results {
+		if name := commands[i].Capture; name != "" && result.Err == nil {
+			a.result.capture(name, result.Output)
+		}
+	}
+
+	failed := slices.ContainsFunc(results, func(result hooks.Result) bool { return result.Err != nil })
+	if len(commands) > 1 || (failed && err == nil) {
+		msg.Finfo(a.Stdout, "Hook %s summary:", stage)
+		for _, result := range results {
+			if result.Err != nil {
+				msg.Ferror(a.Stdout, "%s (%s)", result.Command, result.Duration.Round(time.Millisecond))
+			} else {
+				msg.Fsuccess(a.Stdout, "%s (%s)", result.Command, result.Duration.Round(time.Millisecond))
+			}
+		}
+		for _, command := range commands[len(results):] {
+			msg.Fwarn(a.Stdout, "%s (skipped)", command.Run)
+		}
+	}
+
+	return err
+}
+
+// messages is a helper that returns a copy of the config with just the commit and
+// tag messages rendered for the bump from current to next, using the output
+// captured by the hooks so far.
+func (a App) messages(current, next semver.Version) (config.Config, error) {
+	cfg := a.Cfg
+	cfg.Files = nil // Already rendered
+	if err := cfg.Render(current.String(), next.String(), a.captured()); err != nil {
+		return config.Config{}, err
+	}
+	return cfg, nil
+}
+
+// captured returns the output captured by the hooks of the bump in progress, by
+// name. Every capture in the config is there, empty if its hook hasn't run (or
+// it's a dry run) so the messages can always be rendered.
+func (a App) captured() map[string]string {
+	captured := make(map[string]string)
+	for _, name := range a.Cfg.Hooks.Captures(hooks.StagePreTag) {
+		captured[name] = ""
+	}
+	if a.result != nil {
+		maps.Copy(captured, a.result.Capture)
+	}
+	return captured
+}
+
+// hookVars returns the version context of the bump in progress for hooks.
+func (a App) hookVars() hooks.Vars {
+	var vars hooks.Vars
+	if a.result != nil {
+		vars.Current, vars.Next, vars.Tag = a.result.Current, a.result.Next, a.result.Tag
+	}
+	return vars
+}
+
+// stageError records which stage of a bump an error happened at, for the
+// on-error hook, it's otherwise the same as the error it wraps.
+type stageError struct {
+	err   error
+	stage string // e.g. "pre-commit" or "push"
+}
+
+// Error implements the error interface for a stageError.
+func (e *stageError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+// failedStage returns the stage of a bump err happened at, or "bump" if it isn't known.
+func failedStage(err error) string {
+	var failed *stageError
+	if errors.As(err, &failed) {
+		return failed.stage
+	}
+	return "bump"
+}
+
+// interrupted is a helper that reports the bump was interrupted (ctx was cancelled)
+// during the stage err happened at, and returns the error to carry on with instead.
+//
+// Whatever err was, it's just the knock on effect of the interruption.
+func (a App) interrupted(ctx context.Context, err error) error {
+	stage := failedStage(err)
+	msg.Fwarn(a.Stdout, "Interrupted during %s", stage)
+	return &stageError{stage: stage, err: fmt.Errorf("interrupted during %s: %w", stage, context.Cause(ctx))}
+}
+
+// atStage is a helper that records err happened at stage, unless it already
+// knows a more specific one.
+func atStage(stage string, err error) error {
+	var failed *stageError
+	if errors.As(err, &failed) {
+		return err
+	}
+	return &stageError{stage: stage, err: err}
+}
+
+// signing returns how commits and tags should be signed, as set in the config file.
+func (a App) signing() git.Signing {
+	format := a.Cfg.Git.SigningFormat
+	if format == config.SigningFormatGPG {
+		format = "openpgp" // What git calls it
+	}
+	return git.Signing{Key: a.Cfg.Git.SigningKey, Format: format}
 }
 
 // ensureRepo is a helper that will error if the current directory is not
 // a git repo.
-func (a App) ensureRepo() error {
-	if !git.IsRepo() {
+func (a App) ensureRepo(ctx context.Context) error {
+	if !a.Repo.IsRepo(ctx) {
 		return errors.New("not a git repo")
 	}
 	return nil
@@ -380,8 +1674,8 @@ func (a App) ensureRepo() error {
 
 // ensureBumpable is a helper that will error if the current git state is not
 // "bumpable", that is we're on the default branch, and the working tree is clean.
-func (a App) ensureBumpable() error {
-	dirty, err := git.IsDirty()
+func (a App) ensureBumpable(ctx context.Context) error {
+	dirty, err := a.Repo.IsDirty(ctx)
 	if err != nil {
 		return err
 	}
@@ -389,7 +1683,7 @@ func (a App) ensureBumpable() error {
 		return errors.New("working tree is not clean")
 	}
 
-	branch, err := git.Branch()
+	branch, err := a.Repo.Branch(ctx)
 	if err != nil {
 		return err
 	}
@@ -405,6 +1699,63 @@ func (a App) ensureBumpable() error {
 	return nil
 }
 
+// ensureWritable is a helper that will error if the repo can only be read, i.e. it's
+// a [git.Native] because git isn't installed, so a bump or undo fails before changing
+// anything rather than part way through. action is what's being done e.g. "bumping".
+func (a App) ensureWritable(action string) error {
+	if _, native := a.Repo.(*git.Native); native {
+		return fmt.Errorf("%s is %w, install git and try again", action, git.ErrUnsupported)
+	}
+	return nil
+}
+
+// ensureFiles is a helper that will error if any [[file]] in the config file doesn't
+// exist. It's only needed by the commands that use them, so isn't part of New.
+func (a App) ensureFiles() error {
+	if !a.replaceMode {
+		return nil
+	}
+	if err := a.Cfg.ValidateFiles(a.dir); err != nil {
+		return fmt.Errorf("invalid config file %s:\n%w", a.path(a.configFile), err)
+	}
+	return nil
+}
+
+// ensureChangelog is a helper that will error if a Keep a Changelog style changelog
+// is configured but has nothing under Unreleased, unless allowEmpty is true.
+func (a App) ensureChangelog(allowEmpty bool) error {
+	if a.Cfg.Changelog.Path == "" || a.Cfg.Changelog.Style != changelog.StyleKeepAChangelog {
+		return nil
+	}
+
+	contents, err := os.ReadFile(a.path(a.Cfg.Changelog.Path))
+	if err != nil {
+		return fmt.Errorf("could not read changelog: %w", err)
+	}
+
+	_, err = changelog.Unreleased(contents)
+	if err != nil {
+		if errors.Is(err, changelog.ErrEmptyUnreleased) && allowEmpty {
+			return nil
+		}
+		if errors.Is(err, changelog.ErrEmptyUnreleased) {
+			return fmt.Errorf("%s: %w, add some entries or pass --allow-empty-changelog", a.Cfg.Changelog.Path, err)
+		}
+		return fmt.Errorf("%s: %w", a.Cfg.Changelog.Path, err)
+	}
+
+	return nil
+}
+
+// path is a helper that returns the path on disk of name, which is relative to the
+// config file unless it's absolute.
+func (a App) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(a.dir, filepath.FromSlash(name))
+}
+
 func exists(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err != nil {
@@ -2,14 +2,22 @@ package app //nolint: testpackage // We need access to some internals
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"go.followtheprocess.codes/tag/changelog"
 	"go.followtheprocess.codes/tag/config"
 	"go.followtheprocess.codes/tag/git"
 )
@@ -123,6 +131,7 @@ func setup(t *testing.T) (string, func()) {
 func newTestApp(out io.Writer) App {
 	app := App{
 		Stdout: out,
+		Repo:   git.Exec{},
 		Cfg: config.Config{
 			Version: "0.1.0",
 			Files: []config.File{
@@ -148,7 +157,7 @@ func TestAppLatest(t *testing.T) {
 	out := &bytes.Buffer{}
 	app := newTestApp(out)
 
-	err = app.Latest()
+	err = app.Latest(t.Context())
 	if err != nil {
 		t.Fatalf("app.Latest returned an error: %v", err)
 	}
@@ -169,7 +178,7 @@ func TestAppList(t *testing.T) {
 	out := &bytes.Buffer{}
 	app := newTestApp(out)
 
-	err = app.List(10)
+	err = app.List(t.Context(), 10)
 	if err != nil {
 		t.Fatalf("app.List returned an error: %v", err)
 	}
@@ -179,6 +188,126 @@ func TestAppList(t *testing.T) {
 	}
 }
 
+func TestAppListJSON(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+	out := &bytes.Buffer{}
+	app := newTestApp(out).WithJSON(true)
+
+	err = app.List(t.Context(), 10)
+	if err != nil {
+		t.Fatalf("app.List returned an error: %v", err)
+	}
+
+	var tags []tagJSON
+	if err = json.Unmarshal(out.Bytes(), &tags); err != nil {
+		t.Fatalf("app.List did not output valid JSON: %v\n%s", err, out.String())
+	}
+
+	if len(tags) != 1 {
+		t.Fatalf("Expected 1 tag, got %d", len(tags))
+	}
+
+	head, err := (git.Exec{}).RevParse(t.Context(), "HEAD")
+	if err != nil {
+		t.Fatalf("git.RevParse returned an error: %v", err)
+	}
+
+	got := tags[0]
+	if got.Name != initialVersion || got.Commit != head || got.Message != "test tag" || got.Date.IsZero() {
+		t.Errorf("Wrong tag: %#v", got)
+	}
+
+	want := &versionJSON{Major: 0, Minor: 1, Patch: 0}
+	if got.Version == nil || *got.Version != *want {
+		t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", got.Version, want)
+	}
+}
+
+func TestAppLatestJSON(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+	out := &bytes.Buffer{}
+	app := newTestApp(out).WithJSON(true)
+
+	err = app.Latest(t.Context())
+	if err != nil {
+		t.Fatalf("app.Latest returned an error: %v", err)
+	}
+
+	var tag tagJSON
+	if err = json.Unmarshal(out.Bytes(), &tag); err != nil {
+		t.Fatalf("app.Latest did not output valid JSON: %v\n%s", err, out.String())
+	}
+
+	if tag.Name != initialVersion || tag.Version == nil || tag.Version.Minor != 1 {
+		t.Errorf("Wrong tag: %#v", tag)
+	}
+}
+
+func TestAppBumpJSON(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	app, err := New(tmp, "", stdout, stderr)
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app = app.WithJSON(true)
+
+	if err = app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+
+	var got bumpResult
+	if err = json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("app.Minor did not output valid JSON: %v\n%s", err, stdout.String())
+	}
+
+	head, err := (git.Exec{}).RevParse(t.Context(), "HEAD")
+	if err != nil {
+		t.Fatalf("git.RevParse returned an error: %v", err)
+	}
+
+	want := bumpResult{
+		Current: "0.1.0",
+		Next:    "0.2.0",
+		Tag:     "v0.2.0",
+		Commit:  head,
+		Files: []fileJSON{
+			{Path: "README.md", Replacements: 1},
+			{Path: ".tag.toml", Replacements: 1},
+		},
+		Hooks: []string{"pre-replace", "pre-commit", "pre-tag"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", got, want)
+	}
+
+	// Progress messages must not end up mixed in with the JSON
+	if !strings.Contains(stderr.String(), "Issuing new tag v0.2.0") {
+		t.Errorf("Expected progress on stderr, got:\n%s", stderr.String())
+	}
+}
+
 func TestAppMajor(t *testing.T) {
 	tmp, teardown := setup(t)
 	defer teardown()
@@ -189,12 +318,12 @@ func TestAppMajor(t *testing.T) {
 	}
 	appOut := &bytes.Buffer{}
 	appErr := &bytes.Buffer{}
-	app, err := New(tmp, appOut, appErr)
+	app, err := New(tmp, "", appOut, appErr)
 	if err != nil {
 		t.Fatalf("app.New returned an error: %v", err)
 	}
 
-	err = app.Major(false, true, false)
+	err = app.Major(t.Context(), BumpOptions{Force: true})
 	if err != nil {
 		t.Fatalf("app.Major returned an error: %v", err)
 	}
@@ -228,6 +357,15 @@ func TestAppMajor(t *testing.T) {
 		t.Errorf("Wrong tag template in replaced config file. Got %s, wanted %s", cfg.Git.TagTemplate, defaultTagTemplate)
 	}
 
+	// Check the config file was edited in place, not re-serialised
+	rawCfg, err := os.ReadFile(filepath.Join(tmp, ".tag.toml"))
+	if err != nil {
+		t.Fatalf("Could not read replaced config file: %v", err)
+	}
+	if !strings.Contains(string(rawCfg), "\n\tversion = '1.0.0'\n") {
+		t.Errorf("Config file was not edited in place:\n%s", string(rawCfg))
+	}
+
 	// Check it's made the appropriate commit
 	gitLog := exec.Command("git", "log", "--oneline")
 	stdout, err := gitLog.CombinedOutput()
@@ -240,7 +378,7 @@ func TestAppMajor(t *testing.T) {
 	}
 
 	// Check the working tree is clean
-	dirty, err := git.IsDirty()
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
 	if err != nil {
 		t.Fatalf("git.IsDirty returned an error: %v", err)
 	}
@@ -249,7 +387,7 @@ func TestAppMajor(t *testing.T) {
 	}
 
 	// Check the latest tag is correct
-	latest, err := git.LatestTag()
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
 	if err != nil {
 		t.Errorf("Could not get latest tag: %v", err)
 	}
@@ -268,12 +406,12 @@ func TestAppMajorDryRun(t *testing.T) {
 	}
 	appOut := &bytes.Buffer{}
 	appErr := &bytes.Buffer{}
-	app, err := New(tmp, appOut, appErr)
+	app, err := New(tmp, "", appOut, appErr)
 	if err != nil {
 		t.Fatalf("app.New returned an error: %v", err)
 	}
 
-	err = app.Major(false, true, true)
+	err = app.Major(t.Context(), BumpOptions{Force: true, DryRun: true})
 	if err != nil {
 		t.Fatalf("app.Major returned an error: %v", err)
 	}
@@ -300,7 +438,7 @@ func TestAppMajorDryRun(t *testing.T) {
 	}
 
 	// Check the working tree is clean
-	dirty, err := git.IsDirty()
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
 	if err != nil {
 		t.Fatalf("git.IsDirty returned an error: %v", err)
 	}
@@ -309,7 +447,7 @@ func TestAppMajorDryRun(t *testing.T) {
 	}
 
 	// Check the latest tag is correct
-	latest, err := git.LatestTag()
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
 	if err != nil {
 		t.Errorf("Could not get latest tag: %v", err)
 	}
@@ -328,12 +466,12 @@ func TestAppMinor(t *testing.T) {
 	}
 	appOut := &bytes.Buffer{}
 	appErr := &bytes.Buffer{}
-	app, err := New(tmp, appOut, appErr)
+	app, err := New(tmp, "", appOut, appErr)
 	if err != nil {
 		t.Fatalf("app.New returned an error: %v", err)
 	}
 
-	err = app.Minor(false, true, false)
+	err = app.Minor(t.Context(), BumpOptions{Force: true})
 	if err != nil {
 		t.Fatalf("app.Minor returned an error: %v", err)
 	}
@@ -379,7 +517,7 @@ func TestAppMinor(t *testing.T) {
 	}
 
 	// Check the working tree is clean
-	dirty, err := git.IsDirty()
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
 	if err != nil {
 		t.Fatalf("git.IsDirty returned an error: %v", err)
 	}
@@ -388,7 +526,7 @@ func TestAppMinor(t *testing.T) {
 	}
 
 	// Check the latest tag is correct
-	latest, err := git.LatestTag()
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
 	if err != nil {
 		t.Errorf("Could not get latest tag: %v", err)
 	}
@@ -407,12 +545,12 @@ func TestAppMinorDryRun(t *testing.T) {
 	}
 	appOut := &bytes.Buffer{}
 	appErr := &bytes.Buffer{}
-	app, err := New(tmp, appOut, appErr)
+	app, err := New(tmp, "", appOut, appErr)
 	if err != nil {
 		t.Fatalf("app.New returned an error: %v", err)
 	}
 
-	err = app.Minor(false, true, true)
+	err = app.Minor(t.Context(), BumpOptions{Force: true, DryRun: true})
 	if err != nil {
 		t.Fatalf("app.Minor returned an error: %v", err)
 	}
@@ -439,7 +577,7 @@ func TestAppMinorDryRun(t *testing.T) {
 	}
 
 	// Check the working tree is clean
-	dirty, err := git.IsDirty()
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
 	if err != nil {
 		t.Fatalf("git.IsDirty returned an error: %v", err)
 	}
@@ -448,7 +586,7 @@ func TestAppMinorDryRun(t *testing.T) {
 	}
 
 	// Check the latest tag is correct
-	latest, err := git.LatestTag()
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
 	if err != nil {
 		t.Errorf("Could not get latest tag: %v", err)
 	}
@@ -467,12 +605,12 @@ func TestAppPatch(t *testing.T) {
 	}
 	appOut := &bytes.Buffer{}
 	appErr := &bytes.Buffer{}
-	app, err := New(tmp, appOut, appErr)
+	app, err := New(tmp, "", appOut, appErr)
 	if err != nil {
 		t.Fatalf("app.New returned an error: %v", err)
 	}
 
-	err = app.Patch(false, true, false)
+	err = app.Patch(t.Context(), BumpOptions{Force: true})
 	if err != nil {
 		t.Fatalf("app.Patch returned an error: %v", err)
 	}
@@ -518,7 +656,7 @@ func TestAppPatch(t *testing.T) {
 	}
 
 	// Check the working tree is clean
-	dirty, err := git.IsDirty()
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
 	if err != nil {
 		t.Fatalf("git.IsDirty returned an error: %v", err)
 	}
@@ -527,7 +665,7 @@ func TestAppPatch(t *testing.T) {
 	}
 
 	// Check the latest tag is correct
-	latest, err := git.LatestTag()
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
 	if err != nil {
 		t.Errorf("Could not get latest tag: %v", err)
 	}
@@ -546,12 +684,12 @@ func TestAppPatchDryRun(t *testing.T) {
 	}
 	appOut := &bytes.Buffer{}
 	appErr := &bytes.Buffer{}
-	app, err := New(tmp, appOut, appErr)
+	app, err := New(tmp, "", appOut, appErr)
 	if err != nil {
 		t.Fatalf("app.New returned an error: %v", err)
 	}
 
-	err = app.Patch(false, true, true)
+	err = app.Patch(t.Context(), BumpOptions{Force: true, DryRun: true})
 	if err != nil {
 		t.Fatalf("app.Patch returned an error: %v", err)
 	}
@@ -578,7 +716,7 @@ func TestAppPatchDryRun(t *testing.T) {
 	}
 
 	// Check the working tree is clean
-	dirty, err := git.IsDirty()
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
 	if err != nil {
 		t.Fatalf("git.IsDirty returned an error: %v", err)
 	}
@@ -587,7 +725,7 @@ func TestAppPatchDryRun(t *testing.T) {
 	}
 
 	// Check the latest tag is correct
-	latest, err := git.LatestTag()
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
 	if err != nil {
 		t.Errorf("Could not get latest tag: %v", err)
 	}
@@ -595,3 +733,2199 @@ func TestAppPatchDryRun(t *testing.T) {
 		t.Errorf("Wrong latest tag: got %s, wanted %s", latest, initialVersion)
 	}
 }
+
+func TestAppPreRelease(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	steps := []struct {
+		bump   func(app App) error
+		name   string
+		readme string
+		tag    string
+	}{
+		{
+			name:   "minor --pre rc",
+			bump:   func(app App) error { return app.Minor(t.Context(), BumpOptions{Pre: "rc", Force: true}) },
+			readme: "Hello, version 0.2.0-rc.1",
+			tag:    "v0.2.0-rc.1",
+		},
+		{
+			name:   "pre",
+			bump:   func(app App) error { return app.Pre(t.Context(), BumpOptions{Force: true}) },
+			readme: "Hello, version 0.2.0-rc.2",
+			tag:    "v0.2.0-rc.2",
+		},
+		{
+			name:   "release",
+			bump:   func(app App) error { return app.Release(t.Context(), BumpOptions{Force: true}) },
+			readme: "Hello, version 0.2.0",
+			tag:    "v0.2.0",
+		},
+	}
+
+	for _, step := range steps {
+		// Each step must see the version written back by the last
+		app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("app.New returned an error: %v", err)
+		}
+
+		if err := step.bump(app); err != nil {
+			t.Fatalf("%s returned an error: %v", step.name, err)
+		}
+
+		readme, err := os.ReadFile("README.md")
+		if err != nil {
+			t.Fatalf("Could not read from replaced README: %v", err)
+		}
+
+		if string(readme) != step.readme {
+			t.Errorf("%s: README replaced incorrectly: got %q, wanted %q", step.name, string(readme), step.readme)
+		}
+
+		latest, err := (git.Exec{}).LatestTag(t.Context(), "")
+		if err != nil {
+			t.Fatalf("Could not get latest tag: %v", err)
+		}
+		if latest != step.tag {
+			t.Errorf("%s: wrong latest tag: got %s, wanted %s", step.name, latest, step.tag)
+		}
+	}
+}
+
+func TestAppPreNotPrerelease(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Pre(t.Context(), BumpOptions{Force: true}); err == nil {
+		t.Error("app.Pre did not return an error on a stable version")
+	}
+
+	if err := app.Release(t.Context(), BumpOptions{Force: true}); err == nil {
+		t.Error("app.Release did not return an error on a stable version")
+	}
+}
+
+func TestAppPreLabelBackwards(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Cfg.Version = "1.3.0-rc.2"
+
+	for _, label := range []string{"beta", "alpha", "RC"} {
+		if _, next, err := app.getBumpVersions(t.Context(), pre, label); err == nil {
+			t.Errorf("getBumpVersions(pre, %q) from 1.3.0-rc.2 gave %s, expected an error as it's lower", label, next)
+		}
+	}
+
+	for label, want := range map[string]string{"": "1.3.0-rc.3", "rc": "1.3.0-rc.3", "rc2": "1.3.0-rc2.1"} {
+		_, next, err := app.getBumpVersions(t.Context(), pre, label)
+		if err != nil {
+			t.Fatalf("getBumpVersions(pre, %q) returned an error: %v", label, err)
+		}
+		if next.String() != want {
+			t.Errorf("getBumpVersions(pre, %q): got %s, wanted %s", label, next, want)
+		}
+	}
+}
+
+func TestComparePrerelease(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "rc.1", b: "rc.1", want: 0},
+		{a: "rc.2", b: "rc.10", want: -1},
+		{a: "beta.1", b: "rc.2", want: -1},
+		{a: "rc.1", b: "beta.9", want: 1},
+		{a: "alpha", b: "alpha.1", want: -1},
+		{a: "1", b: "alpha", want: -1},
+		{a: "rc.1", b: "rc.x", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := comparePrerelease(tt.a, tt.b); got != tt.want {
+				t.Errorf("comparePrerelease(%q, %q): got %d, wanted %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppPreCounterFromTags(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	// Someone has already tagged some release candidates by hand
+	for _, tag := range []string{"v0.2.0-rc.1", "v0.2.0-rc.3", "v0.2.0-beta.7"} {
+		stdout, err := exec.Command("git", "tag", "-a", tag, "-m", "test tag").CombinedOutput()
+		if err != nil {
+			t.Fatalf("Error issuing tag %s to test git repo: %s", tag, string(stdout))
+		}
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	_, next, err := app.getBumpVersions(t.Context(), minor, "rc")
+	if err != nil {
+		t.Fatalf("getBumpVersions returned an error: %v", err)
+	}
+
+	if next.String() != "0.2.0-rc.4" {
+		t.Errorf("Wrong next version: got %s, wanted %s", next, "0.2.0-rc.4")
+	}
+
+	if _, _, err := app.getBumpVersions(t.Context(), minor, "rc.1"); err == nil {
+		t.Error("getBumpVersions did not reject an invalid label")
+	}
+}
+
+func TestAppNext(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		bump    string
+		options NextOptions
+		want    string
+		wantErr bool
+	}{
+		{name: "major", bump: "major", want: "1.0.0\n"},
+		{name: "minor", bump: "minor", want: "0.2.0\n"},
+		{name: "patch", bump: "patch", want: "0.1.1\n"},
+		{name: "pre-release", bump: "minor", options: NextOptions{Pre: "rc"}, want: "0.2.0-rc.1\n"},
+		{
+			name:    "format",
+			bump:    "minor",
+			options: NextOptions{Format: "{{.Next.Major}}.{{.Next.Minor}} {{.Tag}} from {{.Current}}"},
+			want:    "0.2 v0.2.0 from 0.1.0\n",
+		},
+		{name: "not a pre-release", bump: "pre", wantErr: true},
+		{name: "bad format", bump: "minor", options: NextOptions{Format: "{{.Nope}}"}, wantErr: true},
+		{name: "bad bump", bump: "huge", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			app, err := New(tmp, "", out, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("app.New returned an error: %v", err)
+			}
+
+			err = app.Next(t.Context(), tt.bump, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("app.Next returned %v, wanted error: %v", err, tt.wantErr)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("app.Next incorrect stdout: got %q, wanted %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	// Nothing should have changed
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
+	if err != nil {
+		t.Fatalf("git.IsDirty returned an error: %v", err)
+	}
+	if dirty {
+		t.Error("app.Next left the working tree dirty")
+	}
+
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
+	if err != nil {
+		t.Fatalf("git.LatestTag returned an error: %v", err)
+	}
+	if latest != initialVersion {
+		t.Errorf("app.Next created a tag: latest is %s", latest)
+	}
+}
+
+func TestSplitPrerelease(t *testing.T) {
+	tests := []struct {
+		prerelease string
+		label      string
+		counter    int
+	}{
+		{prerelease: "rc.4", label: "rc", counter: 4},
+		{prerelease: "beta.12", label: "beta", counter: 12},
+		{prerelease: "alpha", label: "alpha", counter: 0},
+		{prerelease: "rc.x", label: "rc", counter: 0},
+		{prerelease: "", label: "", counter: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prerelease, func(t *testing.T) {
+			label, counter := splitPrerelease(tt.prerelease)
+			if label != tt.label {
+				t.Errorf("got label %q, wanted %q", label, tt.label)
+			}
+			if counter != tt.counter {
+				t.Errorf("got counter %d, wanted %d", counter, tt.counter)
+			}
+		})
+	}
+}
+
+func TestAppAuto(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	for _, message := range []string{"docs: Fix a typo", "fix: Handle a thing", "feat(cli): Add a thing"} {
+		stdout, err := exec.Command("git", "commit", "--allow-empty", "-m", message).CombinedOutput()
+		if err != nil {
+			t.Fatalf("Error committing to the test git repo: %s", string(stdout))
+		}
+	}
+
+	appOut := &bytes.Buffer{}
+	app, err := New(tmp, "", appOut, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Auto(t.Context(), BumpOptions{Force: true, DryRun: true}); err != nil {
+		t.Fatalf("app.Auto returned an error: %v", err)
+	}
+
+	if !strings.Contains(appOut.String(), "feat(cli): Add a thing") {
+		t.Errorf("Dry run did not print the commit driving the bump: %s", appOut.String())
+	}
+
+	if strings.Contains(appOut.String(), "fix: Handle a thing") {
+		t.Errorf("Dry run printed a commit that did not drive the bump: %s", appOut.String())
+	}
+
+	if err := app.Auto(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Auto returned an error: %v", err)
+	}
+
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
+	if err != nil {
+		t.Fatalf("Could not get latest tag: %v", err)
+	}
+	if latest != "v0.2.0" {
+		t.Errorf("Wrong latest tag: got %s, wanted %s", latest, "v0.2.0")
+	}
+
+	// Now there's nothing new since the tag
+	app, err = New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	if err := app.Auto(t.Context(), BumpOptions{Force: true}); !errors.Is(err, ErrNothingToRelease) {
+		t.Errorf("Expected ErrNothingToRelease, got %v", err)
+	}
+}
+
+func TestInferBumpType(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits []string
+		want    bumpType
+		reasons int
+		wantErr bool
+	}{
+		{
+			name:    "fixes only",
+			commits: []string{"fix: One", "fix(cli): Two", "chore: Three"},
+			want:    patch,
+			reasons: 2,
+		},
+		{
+			name:    "feature wins over fix",
+			commits: []string{"fix: One", "feat: Two"},
+			want:    minor,
+			reasons: 1,
+		},
+		{
+			name:    "bang is major",
+			commits: []string{"feat: One", "fix!: Two"},
+			want:    major,
+			reasons: 1,
+		},
+		{
+			name:    "footer is major",
+			commits: []string{"refactor: One\n\nBREAKING CHANGE: Everything is different"},
+			want:    major,
+			reasons: 1,
+		},
+		{
+			name:    "nothing to release",
+			commits: []string{"docs: One", "Merge branch 'main'"},
+			wantErr: true,
+		},
+		{
+			name:    "no commits",
+			commits: nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits := make([]git.LogEntry, 0, len(tt.commits))
+			for i, message := range tt.commits {
+				commits = append(commits, git.LogEntry{Hash: fmt.Sprint(i), Message: message})
+			}
+
+			got, reasons, err := inferBumpType(commits)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %s, wanted %s", got, tt.want)
+			}
+
+			if len(reasons) != tt.reasons {
+				t.Errorf("got %d reasons, wanted %d", len(reasons), tt.reasons)
+			}
+		})
+	}
+}
+
+func TestAppChangelog(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg, err := os.OpenFile(".tag.toml", os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Could not open .tag.toml: %v", err)
+	}
+	if _, err := cfg.WriteString("\n[changelog]\npath = 'CHANGELOG.md'\n"); err != nil {
+		t.Fatalf("Could not write to .tag.toml: %v", err)
+	}
+	cfg.Close()
+
+	if err := os.WriteFile("CHANGELOG.md", []byte("# Changelog\n"), 0o644); err != nil {
+		t.Fatalf("Could not write CHANGELOG.md: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"commit", "-m", "feat: Add a changelog"},
+		{"commit", "--allow-empty", "-m", "fix(cli): Fix a thing"},
+	} {
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	appOut := &bytes.Buffer{}
+	app, err := New(tmp, "", appOut, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Minor(t.Context(), BumpOptions{Force: true, DryRun: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+
+	if !strings.Contains(appOut.String(), "- **cli:** Fix a thing") {
+		t.Errorf("Dry run did not print the changelog section: %s", appOut.String())
+	}
+
+	if err := app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+
+	contents, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatalf("Could not read CHANGELOG.md: %v", err)
+	}
+
+	for _, want := range []string{"# Changelog\n\n## 0.2.0 - ", "### Features\n\n- Add a changelog", "### Bug Fixes\n\n- **cli:** Fix a thing"} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("CHANGELOG.md missing %q:\n%s", want, string(contents))
+		}
+	}
+
+	// The changelog must be part of the bump commit
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
+	if err != nil {
+		t.Fatalf("git.IsDirty returned an error: %v", err)
+	}
+	if dirty {
+		t.Error("Working tree was left dirty after writing the changelog")
+	}
+
+	stdout, err := exec.Command("git", "show", "--name-only", "--format=", "HEAD").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git show returned an error: %s", string(stdout))
+	}
+	if !strings.Contains(string(stdout), "CHANGELOG.md") {
+		t.Errorf("CHANGELOG.md not part of the bump commit: %s", string(stdout))
+	}
+}
+
+func TestAppChangelogCreated(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg, err := os.OpenFile(".tag.toml", os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Could not open .tag.toml: %v", err)
+	}
+	if _, err := cfg.WriteString("\n[changelog]\npath = 'CHANGELOG.md'\n"); err != nil {
+		t.Fatalf("Could not write to .tag.toml: %v", err)
+	}
+	cfg.Close()
+	if stdout, err := exec.Command("git", "commit", "-am", "feat: Add a changelog").CombinedOutput(); err != nil {
+		t.Fatalf("git commit returned an error: %s", string(stdout))
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+
+	contents, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatalf("Could not read CHANGELOG.md: %v", err)
+	}
+	if !strings.Contains(string(contents), "### Features\n\n- Add a changelog") {
+		t.Errorf("CHANGELOG.md missing the new section:\n%s", string(contents))
+	}
+
+	// The new file must be part of the bump commit, not left untracked
+	status, err := exec.Command("git", "status", "--porcelain").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git status returned an error: %s", string(status))
+	}
+	if len(status) != 0 {
+		t.Errorf("Working tree was left dirty after creating the changelog:\n%s", string(status))
+	}
+
+	stdout, err := exec.Command("git", "show", "--name-only", "--format=", "HEAD").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git show returned an error: %s", string(stdout))
+	}
+	if !strings.Contains(string(stdout), "CHANGELOG.md") {
+		t.Errorf("CHANGELOG.md not part of the bump commit: %s", string(stdout))
+	}
+}
+
+func TestAppKeepAChangelog(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg, err := os.OpenFile(".tag.toml", os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Could not open .tag.toml: %v", err)
+	}
+	if _, err := cfg.WriteString("\n[changelog]\npath = 'CHANGELOG.md'\nstyle = 'keep-a-changelog'\n"); err != nil {
+		t.Fatalf("Could not write to .tag.toml: %v", err)
+	}
+	cfg.Close()
+
+	empty := "# Changelog\n\n## [Unreleased]\n\n### Added\n\n[Unreleased]: https://github.com/o/r/compare/v0.1.0...HEAD\n"
+	if err := os.WriteFile("CHANGELOG.md", []byte(empty), 0o644); err != nil {
+		t.Fatalf("Could not write CHANGELOG.md: %v", err)
+	}
+
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "Add a changelog"}} {
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	err = app.Minor(t.Context(), BumpOptions{Force: true})
+	if !errors.Is(err, changelog.ErrEmptyUnreleased) {
+		t.Fatalf("Expected ErrEmptyUnreleased, got %v", err)
+	}
+
+	// Nothing should have happened
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
+	if err != nil {
+		t.Fatalf("Could not get latest tag: %v", err)
+	}
+	if latest != initialVersion {
+		t.Errorf("Bumped despite an empty changelog: latest tag is %s", latest)
+	}
+
+	if err := app.Minor(t.Context(), BumpOptions{Force: true, AllowEmptyChangelog: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+
+	contents, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatalf("Could not read CHANGELOG.md: %v", err)
+	}
+
+	want := "## [Unreleased]\n\n## [0.2.0] - " + time.Now().Format(time.DateOnly)
+	if !strings.Contains(string(contents), want) {
+		t.Errorf("CHANGELOG.md missing %q:\n%s", want, string(contents))
+	}
+
+	wantLinks := "[Unreleased]: https://github.com/o/r/compare/v0.2.0...HEAD\n[0.2.0]: https://github.com/o/r/compare/v0.1.0...v0.2.0\n"
+	if !strings.HasSuffix(string(contents), wantLinks) {
+		t.Errorf("CHANGELOG.md links not updated:\n%s", string(contents))
+	}
+}
+
+func TestAppRegexReplace(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg := []byte(`
+	version = '0.1.0'
+
+	[[file]]
+	path = 'README.md'
+	search = '''Hello, (?P<sep>version\s*[=:]?\s*)["']?{{.Current}}["']?'''
+	replace = 'Hello, ${sep}{{.Next}}'
+	regex = true
+	`)
+	readme := "Hello, version 0.1.0\nHello, version = '0.1.0'\nHello, version: \"0.1.0\"\nHello, version 0x1y0\n"
+
+	if err := os.WriteFile(".tag.toml", cfg, 0o644); err != nil {
+		t.Fatalf("Could not write .tag.toml: %v", err)
+	}
+	if err := os.WriteFile("README.md", []byte(readme), 0o644); err != nil {
+		t.Fatalf("Could not write README.md: %v", err)
+	}
+
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "Use a regex"}} {
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Patch(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	got, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatalf("Could not read from replaced README: %v", err)
+	}
+
+	want := "Hello, version 0.1.1\nHello, version = 0.1.1\nHello, version: 0.1.1\nHello, version 0x1y0\n"
+	if string(got) != want {
+		t.Errorf("README replaced incorrectly: got %q, wanted %q", string(got), want)
+	}
+}
+
+func TestAppStructuredReplace(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg := []byte(`
+	version = '0.1.0'
+
+	[[file]]
+	path = 'package.json'
+	format = 'json'
+	key = 'version'
+
+	[[file]]
+	path = 'pyproject.toml'
+	format = 'toml'
+	key = 'tool.poetry.version'
+	`)
+	packageJSON := "{\n  \"name\": \"thing\",\n  \"version\": \"0.1.0\",\n  \"dependencies\": {\"other\": \"0.1.0\"}\n}\n"
+	pyproject := "[tool.poetry]\n# The version\nversion = \"0.1.0\"\n\n[tool.other]\nversion = \"0.1.0\"\n"
+
+	files := map[string]string{".tag.toml": string(cfg), "package.json": packageJSON, "pyproject.toml": pyproject}
+	for name, contents := range files {
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatalf("Could not write %s: %v", name, err)
+		}
+	}
+
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "Use structured files"}} {
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+
+	want := map[string]string{
+		"package.json":   strings.Replace(packageJSON, `"version": "0.1.0"`, `"version": "0.2.0"`, 1),
+		"pyproject.toml": strings.Replace(pyproject, `version = "0.1.0"`, `version = "0.2.0"`, 1),
+	}
+	for name, contents := range want {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Could not read %s: %v", name, err)
+		}
+		if string(got) != contents {
+			t.Errorf("%s replaced incorrectly: got %q, wanted %q", name, string(got), contents)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "docs/*.md", name: "docs/index.md", want: true},
+		{pattern: "docs/*.md", name: "docs/guide/index.md", want: false},
+		{pattern: "docs/**/*.md", name: "docs/index.md", want: true},
+		{pattern: "docs/**/*.md", name: "docs/guide/deep/index.md", want: true},
+		{pattern: "docs/**/*.md", name: "other/index.md", want: false},
+		{pattern: "charts/*/Chart.yaml", name: "charts/api/Chart.yaml", want: true},
+		{pattern: "charts/*/Chart.yaml", name: "charts/Chart.yaml", want: false},
+		{pattern: "**", name: "anything/at/all", want: true},
+		{pattern: "**/go.mod", name: "go.mod", want: true},
+		{pattern: "v?.txt", name: "v1.txt", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got := matchGlob(strings.Split(tt.pattern, "/"), strings.Split(tt.name, "/"))
+			if got != tt.want {
+				t.Errorf("matchGlob(%q, %q) = %v, wanted %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppGlobReplace(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg := []byte(`
+	version = '0.1.0'
+
+	[[file]]
+	path = 'docs/**/*.md'
+	search = 'version {{.Current}}'
+	`)
+
+	files := map[string]string{
+		".tag.toml":            string(cfg),
+		".gitignore":           "docs/build/\n",
+		"docs/index.md":        "Install version 0.1.0, yes version 0.1.0",
+		"docs/guide/deep.md":   "Deep down it's version 0.1.0",
+		"docs/guide/other.md":  "No version here",
+		"docs/build/output.md": "Generated from version 0.1.0",
+	}
+	for name, contents := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatalf("Could not create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatalf("Could not write %s: %v", name, err)
+		}
+	}
+
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "Add docs"}} {
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	appOut := &bytes.Buffer{}
+	app, err := New(tmp, "", appOut, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Patch(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	want := map[string]string{
+		"docs/index.md":        "Install version 0.1.1, yes version 0.1.1",
+		"docs/guide/deep.md":   "Deep down it's version 0.1.1",
+		"docs/guide/other.md":  "No version here",
+		"docs/build/output.md": "Generated from version 0.1.0", // Ignored
+	}
+	for name, contents := range want {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Could not read %s: %v", name, err)
+		}
+		if string(got) != contents {
+			t.Errorf("%s replaced incorrectly: got %q, wanted %q", name, string(got), contents)
+		}
+	}
+
+	wantOut := "Replacing 2 occurrence(s) in " + filepath.FromSlash("docs/index.md")
+	if !strings.Contains(appOut.String(), wantOut) {
+		t.Errorf("Expected %q in output, got %s", wantOut, appOut.String())
+	}
+}
+
+func TestAppModule(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg := `version = '0.1.0'
+
+[[file]]
+path = 'README.md'
+search = 'Hello, version {{.Current}}'
+
+[[module]]
+path = 'api'
+version = '1.2.0'
+
+[[module.file]]
+path = 'version.txt'
+search = 'api {{.Current}}'
+
+[[module]]
+name = 'worker'
+path = 'services/worker'
+version = '0.4.1'
+`
+
+	files := map[string]string{
+		".tag.toml":                   cfg,
+		"api/version.txt":             "api 1.2.0",
+		"services/worker/version.txt": "worker 0.4.1",
+	}
+	for name, contents := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatalf("Could not create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatalf("Could not write %s: %v", name, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"commit", "-m", "Add modules"},
+		{"tag", "-a", "api/v1.2.0", "-m", "api/v1.2.0"},
+		{"tag", "-a", "services/worker/v0.4.1", "-m", "services/worker/v0.4.1"},
+	} {
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if _, err = app.WithModule("missing"); err == nil {
+		t.Fatal("Expected an error selecting a missing module, got nil")
+	}
+
+	api, err := app.WithModule("api")
+	if err != nil {
+		t.Fatalf("WithModule returned an error: %v", err)
+	}
+
+	if err = api.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+
+	got, err := os.ReadFile("api/version.txt")
+	if err != nil {
+		t.Fatalf("Could not read api/version.txt: %v", err)
+	}
+	if string(got) != "api 1.3.0" {
+		t.Errorf("api/version.txt replaced incorrectly: got %q, wanted %q", string(got), "api 1.3.0")
+	}
+
+	updated, err := config.Load(".tag.toml")
+	if err != nil {
+		t.Fatalf("Could not load config: %v", err)
+	}
+	if updated.Version != "0.1.0" {
+		t.Errorf("Top level version should not have changed, got %s", updated.Version)
+	}
+	if updated.Modules[0].Version != "1.3.0" {
+		t.Errorf("Wrong api module version: got %s, wanted %s", updated.Modules[0].Version, "1.3.0")
+	}
+	if updated.Modules[1].Version != "0.4.1" {
+		t.Errorf("Worker module version should not have changed, got %s", updated.Modules[1].Version)
+	}
+
+	tests := []struct {
+		module string
+		want   string
+	}{
+		{module: "api", want: "api/v1.3.0"},
+		{module: "worker", want: "services/worker/v0.4.1"},
+	}
+
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		app, err := New(tmp, "", out, &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("app.New returned an error: %v", err)
+		}
+		app, err = app.WithModule(tt.module)
+		if err != nil {
+			t.Fatalf("WithModule returned an error: %v", err)
+		}
+		if err = app.Latest(t.Context()); err != nil {
+			t.Fatalf("app.Latest returned an error: %v", err)
+		}
+		if strings.TrimSpace(out.String()) != tt.want {
+			t.Errorf("Latest for module %q: got %q, wanted %q", tt.module, strings.TrimSpace(out.String()), tt.want)
+		}
+	}
+}
+
+func TestAppGoModule(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	files := map[string]string{
+		".tag.toml":    "version = '1.4.2'\n\n[go]\nrewrite-module-path = true\n",
+		"go.mod":       "module example.com/lib\n\ngo 1.26\n",
+		"cmd/main.go":  "package main\n\nimport \"example.com/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib.go":       "package lib\n\nfunc Do() {}\n",
+		"untouched.md": "example.com/lib",
+	}
+	for name, contents := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatalf("Could not create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatalf("Could not write %s: %v", name, err)
+		}
+	}
+
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "Add a go module"}} {
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err = app.Major(t.Context(), BumpOptions{Force: true, DryRun: true}); err != nil {
+		t.Fatalf("app.Major returned an error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Would change module path example.com/lib to example.com/lib/v2") {
+		t.Errorf("Dry run did not report the module path change:\n%s", out.String())
+	}
+
+	if err = app.Major(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Major returned an error: %v", err)
+	}
+
+	want := map[string]string{
+		"go.mod":       "module example.com/lib/v2\n\ngo 1.26\n",
+		"cmd/main.go":  "package main\n\nimport \"example.com/lib/v2\"\n\nfunc main() { lib.Do() }\n",
+		"untouched.md": "example.com/lib",
+	}
+	for name, contents := range want {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Could not read %s: %v", name, err)
+		}
+		if string(got) != contents {
+			t.Errorf("%s rewritten incorrectly: got %q, wanted %q", name, string(got), contents)
+		}
+	}
+}
+
+func TestAppUndo(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	gitRun := func(args ...string) string {
+		t.Helper()
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+		return strings.TrimSpace(string(stdout))
+	}
+
+	before := gitRun("rev-parse", "HEAD")
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err = app.Patch(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	// Reload as the version in the config file has changed
+	app, err = New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err = app.Undo(t.Context(), UndoOptions{Force: true}); err != nil {
+		t.Fatalf("app.Undo returned an error: %v", err)
+	}
+
+	if head := gitRun("rev-parse", "HEAD"); head != before {
+		t.Errorf("HEAD was not reset: got %s, wanted %s", head, before)
+	}
+	if tags := gitRun("tag", "--list"); tags != "v0.1.0" {
+		t.Errorf("Wrong tags after undo: got %q, wanted %q", tags, "v0.1.0")
+	}
+
+	cfg, err := config.Load(filepath.Join(tmp, ".tag.toml"))
+	if err != nil {
+		t.Fatalf("Could not read config file: %v", err)
+	}
+	if cfg.Version != "0.1.0" {
+		t.Errorf("Version not restored: got %s, wanted %s", cfg.Version, "0.1.0")
+	}
+
+	readme, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatalf("Could not read README: %v", err)
+	}
+	if string(readme) != "Hello, version 0.1.0" {
+		t.Errorf("README not restored: got %q", string(readme))
+	}
+
+	// Nothing to undo now, v0.1.0 was tagged on a commit tag didn't make
+	app, err = New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	if err = app.Undo(t.Context(), UndoOptions{Force: true}); err == nil {
+		t.Error("Expected an error undoing a tag not made by a bump, got nil")
+	}
+
+	// A pushed bump needs --remote
+	remote := t.TempDir()
+	gitRun("init", "--bare", remote)
+	gitRun("remote", "add", "origin", remote)
+	gitRun("push", "--set-upstream", "origin", "main")
+
+	if err = app.Patch(t.Context(), BumpOptions{Force: true, Push: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	app, err = New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err = app.Undo(t.Context(), UndoOptions{Force: true}); err == nil {
+		t.Fatal("Expected an error undoing a pushed tag without --remote, got nil")
+	}
+
+	if err = app.Undo(t.Context(), UndoOptions{Force: true, Remote: true}); err != nil {
+		t.Fatalf("app.Undo returned an error: %v", err)
+	}
+
+	if remoteTags := gitRun("ls-remote", "--tags", "origin"); strings.Contains(remoteTags, "v0.1.1") {
+		t.Errorf("Tag was not deleted from the remote: %s", remoteTags)
+	}
+}
+
+func TestAppPush(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	gitRun := func(args ...string) string {
+		t.Helper()
+		stdout, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+		return strings.TrimSpace(string(stdout))
+	}
+
+	for _, name := range []string{"origin", "mirror"} {
+		remote := t.TempDir()
+		gitRun("init", "--bare", remote)
+		gitRun("remote", "add", name, remote)
+	}
+
+	// --follow-tags would push this along with the branch
+	gitRun("tag", "-a", "stray", "-m", "Not a release")
+
+	app, err := New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err = app.Patch(t.Context(), BumpOptions{Force: true, Push: true, Remote: "mirror"}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	if refs := gitRun("ls-remote", "origin"); refs != "" {
+		t.Errorf("Expected nothing pushed to origin, got:\n%s", refs)
+	}
+	refs := gitRun("ls-remote", "mirror")
+	for _, want := range []string{"refs/heads/main", "refs/tags/v0.1.1"} {
+		if !strings.Contains(refs, want) {
+			t.Errorf("Expected %s on mirror, got:\n%s", want, refs)
+		}
+	}
+	for _, unwanted := range []string{"refs/tags/stray", "refs/tags/v0.1.0"} {
+		if strings.Contains(refs, unwanted) {
+			t.Errorf("Did not expect %s on mirror, got:\n%s", unwanted, refs)
+		}
+	}
+
+	// Only the tag, to every remote
+	app, err = New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Cfg.Git.PushMode = config.PushModeTag
+	app.Cfg.Git.PushRemotes = []string{"origin", "mirror"}
+
+	if err = app.Patch(t.Context(), BumpOptions{Force: true, Push: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	if heads := gitRun("ls-remote", "--heads", "origin"); heads != "" {
+		t.Errorf("Expected no branches on origin, got:\n%s", heads)
+	}
+	if refs := gitRun("ls-remote", "--tags", "origin"); !strings.Contains(refs, "refs/tags/v0.1.2") {
+		t.Errorf("Expected v0.1.2 on origin, got:\n%s", refs)
+	}
+	if refs := gitRun("ls-remote", "mirror"); !strings.Contains(refs, "refs/tags/v0.1.2") {
+		t.Errorf("Expected v0.1.2 on mirror, got:\n%s", refs)
+	}
+}
+
+func TestAppHookVars(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Cfg.Hooks.PreTag = config.NewHook("echo tagging {{.Tag}} from $TAG_CURRENT in $TAG_STAGE")
+
+	if err = app.Minor(t.Context(), BumpOptions{Force: true, DryRun: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+	if want := "Would run hook Pre Tag: echo tagging v0.2.0 from $TAG_CURRENT in $TAG_STAGE"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in dry run output, got:\n%s", want, out.String())
+	}
+
+	out.Reset()
+	if err = app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v", err)
+	}
+	if want := "tagging v0.2.0 from 0.1.0 in pre-tag\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+	}
+}
+
+func TestAppHookStages(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	remote := t.TempDir()
+	for _, args := range [][]string{{"init", "--bare", remote}, {"remote", "add", "origin", remote}} {
+		if stdout, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v returned an error: %s", args, string(stdout))
+		}
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Cfg.Hooks = config.Hooks{
+		PostTag:  config.NewHook("echo post-tag {{.Tag}}"),
+		PostPush: config.NewHook("echo post-push $TAG_TAG"),
+		OnError:  config.NewHook("echo on-error at $TAG_FAILED_STAGE"),
+	}
+
+	if err = app.Patch(t.Context(), BumpOptions{Force: true, Push: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	for _, want := range []string{"post-tag v0.1.1\n", "post-push v0.1.1\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "on-error") {
+		t.Errorf("on-error hook ran after a successful bump:\n%s", out.String())
+	}
+
+	tests := []struct {
+		hooks config.Hooks
+		name  string
+		want  string // Expected output of the on-error hook
+	}{
+		{
+			name:  "hook",
+			hooks: config.Hooks{PreTag: config.NewHook("exit 1"), OnError: config.NewHook("echo on-error at $TAG_FAILED_STAGE")},
+			want:  "on-error at pre-tag\n",
+		},
+		{
+			name:  "push",
+			hooks: config.Hooks{OnError: config.NewHook("echo on-error at {{.FailedStage}} for $TAG_NEXT")},
+			want:  "on-error at push for 0.1.2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			app, err := New(tmp, "", out, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("app.New returned an error: %v", err)
+			}
+			app.Cfg.Hooks = tt.hooks
+
+			if err = app.Patch(t.Context(), BumpOptions{Force: true, Push: true, Remote: "missing"}); err == nil {
+				t.Fatal("Expected app.Patch to fail")
+			}
+
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("Expected %q in output, got:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestAppHookCommands(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Cfg.Hooks.PreTag = config.Hook{
+		Commands: []config.HookCommand{
+			{Run: "echo building {{.Next}}"},
+			{Run: "exit 1", ContinueOnError: true},
+			{Run: "echo man page", Timeout: "1m"},
+		},
+	}
+
+	if err = app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v\n%s", err, out.String())
+	}
+
+	for _, want := range []string{"building 0.2.0\n", "man page\n", "Hook Pre Tag summary:", "echo building 0.2.0 (", "exit 1 ("} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+
+	// Without continue-on-error the rest are skipped and the bump is undone
+	out.Reset()
+	app, err = New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Cfg.Hooks.PreTag = config.NewHook("exit 1", "echo never")
+
+	if err = app.Minor(t.Context(), BumpOptions{Force: true}); err == nil {
+		t.Fatal("Expected app.Minor to fail")
+	}
+	if want := "echo never (skipped)"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+	}
+	if strings.Contains(out.String(), "never\n") {
+		t.Errorf("Command after the failure ran:\n%s", out.String())
+	}
+}
+
+func TestAppHookCapture(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	cfg := `version = '0.1.0'
+
+[git]
+message-template = 'Bump to {{.Next}}: {{.Hooks.Summary}}'
+tag-template = """v{{.Next}}
+
+{{.Hooks.Notes}}"""
+
+[[hooks.pre-commit]]
+run = 'echo "a summary"'
+capture = 'Summary'
+
+[[hooks.pre-tag]]
+run = 'echo "- Added {{.Next}}"; echo; echo "- Fixed things"'
+capture = 'Notes'
+
+[[file]]
+path = 'README.md'
+search = 'Hello, version {{.Current}}'
+`
+	if err = os.WriteFile(".tag.toml", []byte(cfg), 0o644); err != nil {
+		t.Fatalf("Could not write config file: %v", err)
+	}
+	if stdout, err := exec.Command("git", "commit", "-am", "Capture hooks").CombinedOutput(); err != nil {
+		t.Fatalf("Could not commit config file: %s", stdout)
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	if err = app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v\n%s", err, out.String())
+	}
+
+	// The output is still shown as it runs
+	if want := "- Fixed things\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+	}
+
+	message, err := (git.Exec{}).CommitMessage(t.Context(), "HEAD")
+	if err != nil {
+		t.Fatalf("Could not get the commit message: %v", err)
+	}
+	if want := "Bump to 0.2.0: a summary"; message != want {
+		t.Errorf("Wrong commit message: got %q, wanted %q", message, want)
+	}
+
+	tagMessage, err := (git.Exec{}).TagMessage(t.Context(), "v0.2.0")
+	if err != nil {
+		t.Fatalf("Could not get the tag message: %v", err)
+	}
+	if want := "v0.2.0\n\n- Added 0.2.0\n\n- Fixed things"; tagMessage != want {
+		t.Errorf("Wrong tag message: got %q, wanted %q", tagMessage, want)
+	}
+
+	// Undo can't know what was captured, but still recognises the bump commit
+	app, err = New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	if err = app.Undo(t.Context(), UndoOptions{Force: true}); err != nil {
+		t.Fatalf("app.Undo returned an error: %v\n%s", err, out.String())
+	}
+}
+
+func TestAppInterrupted(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	before, err := (git.Exec{}).RevParse(t.Context(), "HEAD")
+	if err != nil {
+		t.Fatalf("Could not get HEAD: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Cfg.Hooks.PreTag = config.NewHook("sleep 5")
+	app.Cfg.Hooks.OnError = config.NewHook("echo cleaning up after {{.FailedStage}}")
+
+	// Like Ctrl-C part way through the pre-tag hook, after the commit
+	ctx, cancel := context.WithCancelCause(t.Context())
+	defer cancel(nil)
+	time.AfterFunc(200*time.Millisecond, func() { cancel(errors.New("interrupt signal received")) })
+
+	err = app.Minor(ctx, BumpOptions{Force: true})
+	if err == nil {
+		t.Fatal("Expected app.Minor to fail")
+	}
+	if want := "interrupted during pre-tag: interrupt signal received"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected %q in the error, got %v", want, err)
+	}
+
+	for _, want := range []string{
+		"Interrupted during pre-tag",
+		"Reset the bump commit",
+		"Everything was put back, the repo is as it was before the bump",
+		"cleaning up after pre-tag", // The on-error hook still runs
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+
+	if head, err := (git.Exec{}).RevParse(t.Context(), "HEAD"); err != nil || head != before {
+		t.Errorf("HEAD was not put back: got %s, wanted %s (%v)", head, before, err)
+	}
+	readme, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatalf("Could not read README: %v", err)
+	}
+	if string(readme) != "Hello, version 0.1.0" {
+		t.Errorf("README not restored: got %q", string(readme))
+	}
+}
+
+func TestAppRollback(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     string
+		wantOut []string // Lines expected in the rollback report
+	}{
+		{
+			name: "search not found",
+			cfg: `version = '0.1.0'
+
+[[file]]
+path = 'README.md'
+search = 'Hello, version {{.Current}}'
+
+[[file]]
+path = 'other.txt'
+search = 'Not in there {{.Current}}'
+`,
+			wantOut: []string{"Restored README.md"},
+		},
+		{
+			name: "pre-commit hook fails",
+			cfg: `version = '0.1.0'
+
+[changelog]
+path = 'CHANGELOG.md'
+
+[hooks]
+pre-commit = 'exit 1'
+
+[[file]]
+path = 'README.md'
+search = 'Hello, version {{.Current}}'
+`,
+			wantOut: []string{"Restored .tag.toml", "Removed CHANGELOG.md", "Restored README.md"},
+		},
+		{
+			name: "pre-tag hook fails",
+			cfg: `version = '0.1.0'
+
+[hooks]
+pre-tag = 'exit 1'
+
+[[file]]
+path = 'README.md'
+search = 'Hello, version {{.Current}}'
+`,
+			wantOut: []string{"Reset the bump commit", "Restored .tag.toml", "Restored README.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp, teardown := setup(t)
+			defer teardown()
+
+			err := os.Chdir(tmp)
+			if err != nil {
+				t.Fatalf("Could not change dir to tmp: %v", err)
+			}
+
+			if err := os.WriteFile(".tag.toml", []byte(tt.cfg), 0o644); err != nil {
+				t.Fatalf("Could not write .tag.toml: %v", err)
+			}
+			if err := os.WriteFile("other.txt", []byte("Nothing to see here"), 0o644); err != nil {
+				t.Fatalf("Could not write other.txt: %v", err)
+			}
+
+			for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "Configure tag"}} {
+				stdout, err := exec.Command("git", args...).CombinedOutput()
+				if err != nil {
+					t.Fatalf("git %v returned an error: %s", args, string(stdout))
+				}
+			}
+
+			head, err := exec.Command("git", "rev-parse", "HEAD").CombinedOutput()
+			if err != nil {
+				t.Fatalf("Could not get HEAD: %s", string(head))
+			}
+
+			out := &bytes.Buffer{}
+			app, err := New(tmp, "", out, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("app.New returned an error: %v", err)
+			}
+
+			if err = app.Patch(t.Context(), BumpOptions{Force: true}); err == nil {
+				t.Fatal("Expected app.Patch to fail, got nil")
+			}
+
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+				}
+			}
+
+			status, err := exec.Command("git", "status", "--porcelain").CombinedOutput()
+			if err != nil {
+				t.Fatalf("git status returned an error: %s", string(status))
+			}
+			if len(status) != 0 {
+				t.Errorf("Working tree not restored, git status:\n%s", string(status))
+			}
+
+			after, err := exec.Command("git", "rev-parse", "HEAD").CombinedOutput()
+			if err != nil {
+				t.Fatalf("Could not get HEAD: %s", string(after))
+			}
+			if string(after) != string(head) {
+				t.Errorf("HEAD moved: got %s, wanted %s", string(after), string(head))
+			}
+		})
+	}
+}
+
+func TestAppCheck(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err = app.Check(t.Context()); err != nil {
+		t.Fatalf("app.Check returned an error: %v\n%s", err, out.String())
+	}
+
+	// The setup tag wasn't made by tag so the message won't match the template
+	if !strings.Contains(out.String(), `Tag v0.1.0 has message "test tag", tag-template gives "v0.1.0"`) {
+		t.Errorf("Expected a warning about the tag message, got:\n%s", out.String())
+	}
+
+	// After a bump everything should match, including the tag message
+	if err = app.Patch(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	out.Reset()
+	app, err = New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err = app.Check(t.Context()); err != nil {
+		t.Fatalf("app.Check returned an error: %v\n%s", err, out.String())
+	}
+	if strings.Contains(out.String(), "has message") {
+		t.Errorf("Unexpected tag message warning after bump:\n%s", out.String())
+	}
+
+	// Now introduce some drift
+	if err = os.WriteFile("README.md", []byte("Hello, version 0.3.0"), 0o644); err != nil {
+		t.Fatalf("Could not write README.md: %v", err)
+	}
+
+	out.Reset()
+	err = app.Check(t.Context())
+	if !errors.Is(err, ErrVersionDrift) {
+		t.Fatalf("Expected ErrVersionDrift, got %v", err)
+	}
+	if !strings.Contains(out.String(), `Could not find "Hello, version 0.1.1" in README.md`) {
+		t.Errorf("Expected the drifted file to be reported, got:\n%s", out.String())
+	}
+}
+
+func TestAppSigned(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	// Keep the keys out of the repo so the tree stays clean
+	keys := t.TempDir()
+	key := filepath.Join(keys, "id_ed25519")
+	keygen := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "tagtest@gmail.com", "-f", key)
+	if out, err := keygen.CombinedOutput(); err != nil {
+		t.Fatalf("Could not generate ssh key: %s", out)
+	}
+
+	public, err := os.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatalf("Could not read public key: %v", err)
+	}
+
+	allowed := filepath.Join(keys, "allowed_signers")
+	if err = os.WriteFile(allowed, []byte("tagtest@gmail.com "+string(public)), 0o644); err != nil {
+		t.Fatalf("Could not write allowed signers: %v", err)
+	}
+
+	allowedConfig := exec.Command("git", "config", "--local", "gpg.ssh.allowedSignersFile", allowed)
+	if out, err := allowedConfig.CombinedOutput(); err != nil {
+		t.Fatalf("git config returned an error: %s", out)
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	// The setup tag isn't signed
+	if err = app.Verify(t.Context(), ""); err == nil {
+		t.Fatal("Expected verifying an unsigned tag to fail")
+	}
+
+	app.Cfg.Git.SignTags = true
+	app.Cfg.Git.SignCommits = true
+	app.Cfg.Git.SigningFormat = config.SigningFormatSSH
+	app.Cfg.Git.SigningKey = key
+
+	if err = app.Patch(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v\n%s", err, out.String())
+	}
+
+	verifyCommit := exec.Command("git", "verify-commit", "HEAD")
+	if stdout, err := verifyCommit.CombinedOutput(); err != nil {
+		t.Errorf("Bump commit is not signed: %s", stdout)
+	}
+
+	out.Reset()
+	if err = app.Verify(t.Context(), ""); err != nil {
+		t.Fatalf("app.Verify returned an error: %v", err)
+	}
+
+	want := "Tag v0.1.1 has a good signature from tagtest@gmail.com"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+	}
+}
+
+func TestAppNative(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	err := os.Chdir(tmp)
+	if err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	// A bump would create the changelog, it mustn't be left behind untracked
+	cfg, err := os.OpenFile(".tag.toml", os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Could not open .tag.toml: %v", err)
+	}
+	if _, err := cfg.WriteString("\n[changelog]\npath = 'CHANGELOG.md'\n"); err != nil {
+		t.Fatalf("Could not write to .tag.toml: %v", err)
+	}
+	cfg.Close()
+	if stdout, err := exec.Command("git", "commit", "-am", "feat: Add a changelog").CombinedOutput(); err != nil {
+		t.Fatalf("git commit returned an error: %s", string(stdout))
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Repo = git.NewNative(tmp)
+
+	// Reading works without git
+	if err = app.Latest(t.Context()); err != nil {
+		t.Fatalf("app.Latest returned an error: %v", err)
+	}
+	if out.String() != fmt.Sprintln(initialVersion) {
+		t.Errorf("app.Latest incorrect stdout: got %q, wanted %q", out.String(), fmt.Sprintln(initialVersion))
+	}
+
+	out.Reset()
+	if err = app.List(t.Context(), 10); err != nil {
+		t.Fatalf("app.List returned an error: %v", err)
+	}
+	if out.String() != fmt.Sprintln(initialVersion) {
+		t.Errorf("app.List incorrect stdout: got %q, wanted %q", out.String(), fmt.Sprintln(initialVersion))
+	}
+
+	// Anything that writes needs git, and should say so before changing anything
+	tagOnly := App{
+		Stdout: out,
+		Stderr: &bytes.Buffer{},
+		Repo:   git.NewNative(tmp),
+	}
+	attempts := map[string]func() error{
+		"minor":          func() error { return app.Minor(t.Context(), BumpOptions{Force: true}) },
+		"minor dry run":  func() error { return app.Minor(t.Context(), BumpOptions{Force: true, DryRun: true}) },
+		"minor tag only": func() error { return tagOnly.Minor(t.Context(), BumpOptions{Force: true}) },
+		"undo":           func() error { return app.Undo(t.Context(), UndoOptions{Force: true}) },
+	}
+	for name, attempt := range attempts {
+		if err := attempt(); !errors.Is(err, git.ErrUnsupported) {
+			t.Errorf("%s: expected ErrUnsupported, got %v", name, err)
+		}
+	}
+
+	readme, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatalf("Could not read README: %v", err)
+	}
+	if string(readme) != initialReadmeContent {
+		t.Errorf("README changed: got %q, wanted %q", string(readme), initialReadmeContent)
+	}
+	latest, err := (git.Exec{}).LatestTag(t.Context(), "")
+	if err != nil {
+		t.Fatalf("Could not get the latest tag: %v", err)
+	}
+	if latest != initialVersion {
+		t.Errorf("Wrong latest tag: got %s, wanted %s", latest, initialVersion)
+	}
+	if _, err := os.Stat("CHANGELOG.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected CHANGELOG.md not to be created, got %v", err)
+	}
+	if status, err := exec.Command("git", "status", "--porcelain").CombinedOutput(); err != nil || len(status) != 0 {
+		t.Errorf("Expected git to see a clean tree, got %s (%v)", status, err)
+	}
+}
+
+func TestAppSubdirectory(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	sub := filepath.Join(tmp, "docs", "guide")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("Could not create subdirectory: %v", err)
+	}
+	if err := os.Chdir(sub); err != nil {
+		t.Fatalf("Could not change dir to subdirectory: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	app, err := New(sub, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	if !app.replaceMode {
+		t.Fatal("Expected replace mode from a subdirectory, .tag.toml was not found")
+	}
+	app.Cfg.Hooks.PreTag = config.NewHook("pwd")
+
+	if err := app.Patch(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	// Hooks run alongside the config file too
+	if !slices.Contains(strings.Split(out.String(), "\n"), tmp) {
+		t.Errorf("Expected the pre-tag hook to run in %s, got:\n%s", tmp, out.String())
+	}
+
+	readme, err := os.ReadFile(filepath.Join(tmp, "README.md"))
+	if err != nil {
+		t.Fatalf("Could not read from replaced README: %v", err)
+	}
+	if want := "Hello, version 0.1.1"; string(readme) != want {
+		t.Errorf("README replaced incorrectly: got %q, wanted %q", string(readme), want)
+	}
+
+	cfg, err := config.Load(filepath.Join(tmp, ".tag.toml"))
+	if err != nil {
+		t.Fatalf("Could not read replaced config file: %v", err)
+	}
+	if cfg.Version != "0.1.1" {
+		t.Errorf("Wrong version in replaced config file. Got %s, wanted %s", cfg.Version, "0.1.1")
+	}
+}
+
+func TestNewConfigFlag(t *testing.T) {
+	tmp, teardown := setup(t)
+	defer teardown()
+
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatalf("Could not change dir to tmp: %v", err)
+	}
+
+	mv := exec.Command("git", "mv", ".tag.toml", "release.toml")
+	if out, err := mv.CombinedOutput(); err != nil {
+		t.Fatalf("git mv returned an error: %s", string(out))
+	}
+	commit := exec.Command("git", "commit", "-m", "Rename config")
+	if out, err := commit.CombinedOutput(); err != nil {
+		t.Fatalf("git commit returned an error: %s", string(out))
+	}
+
+	sub := filepath.Join(tmp, "docs")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("Could not create subdirectory: %v", err)
+	}
+
+	_, err := New(sub, "missing.toml", &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected a not found error from New with a missing config file, got %v", err)
+	}
+
+	app, err := New(sub, filepath.Join("..", "release.toml"), &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+
+	if err := app.Patch(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v", err)
+	}
+
+	cfg, err := config.Load(filepath.Join(tmp, "release.toml"))
+	if err != nil {
+		t.Fatalf("Could not read replaced config file: %v", err)
+	}
+	if cfg.Version != "0.1.1" {
+		t.Errorf("Wrong version in replaced config file. Got %s, wanted %s", cfg.Version, "0.1.1")
+	}
+
+	if _, err := os.Stat(filepath.Join(tmp, ".tag.toml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no .tag.toml to be written alongside release.toml, got %v", err)
+	}
+
+	dirty, err := (git.Exec{}).IsDirty(t.Context())
+	if err != nil {
+		t.Fatalf("git.IsDirty returned an error: %v", err)
+	}
+	if dirty {
+		t.Error("Working tree was left dirty after bumping with --config")
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	tmp := t.TempDir()
+	cfg := "version = '0.1.0'\n\n[[file]]\npath = 'missing.txt'\nsearch = 'version {{.Current'\n"
+	if err := os.WriteFile(filepath.Join(tmp, ".tag.toml"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("Could not write .tag.toml: %v", err)
+	}
+
+	// Somewhere other than the config file so any change would show
+	t.Chdir(t.TempDir())
+	before, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Could not get working directory: %v", err)
+	}
+
+	_, err = New(tmp, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("Expected an error from New with an invalid config, got nil")
+	}
+
+	if after, err := os.Getwd(); err != nil || after != before {
+		t.Errorf("New changed the working directory from %s to %s (%v)", before, after, err)
+	}
+
+	if want := "could not parse file.search for file missing.txt"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected %q in error, got: %v", want, err)
+	}
+	// Missing files are only a problem for the commands that use them
+	if strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected New not to check missing.txt exists, got: %v", err)
+	}
+}
+
+func TestAppMissingFile(t *testing.T) {
+	app, repo, out := fakeSetup(t, "\n[[file]]\npath = 'missing.txt'\nsearch = 'version {{.Current}}'\n")
+	before := repo.head()
+
+	// Read only commands don't care
+	if err := app.List(t.Context(), 10); err != nil {
+		t.Fatalf("app.List returned an error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "bump", run: func() error { return app.Minor(t.Context(), BumpOptions{Force: true}) }},
+		{name: "check", run: func() error { return app.Check(t.Context()) }},
+		{name: "validate", run: func() error { return app.ValidateConfig(app.dir) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil {
+				t.Fatalf("Expected an error with a missing file, got nil\n%s", out.String())
+			}
+			if want := "file missing.txt does not exist"; !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %q in error, got: %v", want, err)
+			}
+		})
+	}
+
+	if repo.head().hash != before.hash || len(repo.tags) != 1 {
+		t.Error("Expected nothing to change when a file is missing")
+	}
+	if got, err := os.ReadFile(filepath.Join(app.dir, "README.md")); err != nil || string(got) != initialReadmeContent {
+		t.Errorf("Expected README.md to be untouched, got %q (%v)", got, err)
+	}
+}
+
+func TestAppInitThenList(t *testing.T) {
+	tmp := t.TempDir()
+	if err := (App{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}).Init(tmp, false); err != nil {
+		t.Fatalf("app.Init returned an error: %v", err)
+	}
+
+	// The examples in a fresh config mustn't stop tag working straight away
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error for a fresh config: %v", err)
+	}
+	repo, err := newFakeRepo(tmp)
+	if err != nil {
+		t.Fatalf("Could not create fake repo: %v", err)
+	}
+	repo.tag(initialVersion, "test tag")
+	app.Repo = repo
+
+	if err := app.List(t.Context(), 10); err != nil {
+		t.Fatalf("app.List returned an error: %v", err)
+	}
+	if !strings.Contains(out.String(), initialVersion) {
+		t.Errorf("Expected %s listed, got:\n%s", initialVersion, out.String())
+	}
+}
+
+// fakeSetup is a helper like setup, but the repo is a fakeRepo so it needs neither git
+// nor changing directory. The config from setup, with extra appended, and the README
+// are committed and tagged v0.1.0, with a feat commit on top.
+func fakeSetup(t *testing.T, extra string) (App, *fakeRepo, *bytes.Buffer) {
+	t.Helper()
+	tmp := t.TempDir()
+
+	cfg := `version = '0.1.0'
+
+[git]
+default-branch = 'main'
+message-template = 'Bump version {{.Current}} -> {{.Next}}'
+tag-template = 'v{{.Next}}'
+
+[[file]]
+path = 'README.md'
+search = 'Hello, version {{.Current}}'
+` + extra
+
+	if err := os.WriteFile(filepath.Join(tmp, ".tag.toml"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("Could not write .tag.toml: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "README.md"), []byte(initialReadmeContent), 0o644); err != nil {
+		t.Fatalf("Could not write README.md: %v", err)
+	}
+
+	repo, err := newFakeRepo(tmp)
+	if err != nil {
+		t.Fatalf("Could not create fake repo: %v", err)
+	}
+	repo.tag(initialVersion, "test tag")
+	repo.commit("feat: Add a thing", repo.head().files)
+
+	out := &bytes.Buffer{}
+	app, err := New(tmp, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Repo = repo
+
+	return app, repo, out
+}
+
+func TestAppFakeBump(t *testing.T) {
+	app, repo, out := fakeSetup(t, "\n[changelog]\npath = 'CHANGELOG.md'\n")
+	before := repo.head()
+
+	if err := app.Minor(t.Context(), BumpOptions{Force: true}); err != nil {
+		t.Fatalf("app.Minor returned an error: %v\n%s", err, out.String())
+	}
+
+	// Every file the bump wrote, including the new changelog, is staged and committed
+	if want := []string{"README.md", "CHANGELOG.md", ".tag.toml"}; !reflect.DeepEqual(repo.added, want) {
+		t.Errorf("Wrong paths staged: got %v, wanted %v", repo.added, want)
+	}
+
+	head := repo.head()
+	if head.message != "Bump version 0.1.0 -> 0.2.0" {
+		t.Errorf("Wrong bump commit message: got %q", head.message)
+	}
+	if head.files["README.md"] != "Hello, version 0.2.0" {
+		t.Errorf("Wrong README in the bump commit: got %q", head.files["README.md"])
+	}
+	if !strings.Contains(head.files["CHANGELOG.md"], "### Features\n\n- Add a thing") {
+		t.Errorf("CHANGELOG.md missing from the bump commit:\n%s", head.files["CHANGELOG.md"])
+	}
+	if !strings.Contains(head.files[".tag.toml"], "version = '0.2.0'") {
+		t.Errorf("Version not bumped in the committed .tag.toml:\n%s", head.files[".tag.toml"])
+	}
+
+	if tag := repo.tags["v0.2.0"]; tag.commit != head.hash || tag.message != "v0.2.0" {
+		t.Errorf("Wrong tag v0.2.0: %#v", tag)
+	}
+	if dirty, err := repo.IsDirty(t.Context()); err != nil || dirty {
+		t.Errorf("Expected a clean tree after bumping, got dirty=%v (%v)", dirty, err)
+	}
+
+	// Undo needs an App that sees the bumped config
+	app, err := New(app.dir, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Repo = repo
+
+	if err := app.Undo(t.Context(), UndoOptions{Force: true}); err != nil {
+		t.Fatalf("app.Undo returned an error: %v\n%s", err, out.String())
+	}
+
+	if _, ok := repo.tags["v0.2.0"]; ok {
+		t.Error("Expected undo to delete tag v0.2.0")
+	}
+	if repo.head().hash != before.hash {
+		t.Errorf("Expected undo to reset HEAD to %s, got %s", before.hash, repo.head().hash)
+	}
+	if dirty, err := repo.IsDirty(t.Context()); err != nil || dirty {
+		t.Errorf("Expected undo to put every file back, got dirty=%v (%v)", dirty, err)
+	}
+}
+
+func TestAppFakeRollback(t *testing.T) {
+	app, repo, out := fakeSetup(t, "\n[changelog]\npath = 'CHANGELOG.md'\n\n[hooks]\npre-tag = 'exit 1'\n")
+	before := repo.head()
+
+	err := app.Minor(t.Context(), BumpOptions{Force: true})
+	if err == nil {
+		t.Fatal("Expected app.Minor to fail with a failing pre-tag hook")
+	}
+
+	if repo.head().hash != before.hash {
+		t.Errorf("Expected the bump commit to be reset, HEAD is %s not %s", repo.head().hash, before.hash)
+	}
+	if _, ok := repo.tags["v0.2.0"]; ok {
+		t.Error("Expected no tag v0.2.0")
+	}
+	if dirty, err := repo.IsDirty(t.Context()); err != nil || dirty {
+		t.Errorf("Expected every file to be put back, got dirty=%v (%v)", dirty, err)
+	}
+
+	for _, want := range []string{"Reset the bump commit", "Removed CHANGELOG.md", "Restored README.md", "Restored .tag.toml"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestAppFakePush(t *testing.T) {
+	app, repo, out := fakeSetup(t, "")
+	repo.remotes["origin"] = make(map[string]bool)
+
+	if err := app.Patch(t.Context(), BumpOptions{Force: true, Push: true}); err != nil {
+		t.Fatalf("app.Patch returned an error: %v\n%s", err, out.String())
+	}
+	if !repo.remotes["origin"]["v0.1.1"] {
+		t.Fatal("Expected v0.1.1 to be pushed to origin")
+	}
+
+	app, err := New(app.dir, "", out, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("app.New returned an error: %v", err)
+	}
+	app.Repo = repo
+
+	// Pushed, so it takes --remote to undo
+	if err := app.Undo(t.Context(), UndoOptions{Force: true}); err == nil {
+		t.Fatal("Expected app.Undo to refuse to undo a pushed tag without --remote")
+	}
+	if err := app.Undo(t.Context(), UndoOptions{Force: true, Remote: true}); err != nil {
+		t.Fatalf("app.Undo returned an error: %v\n%s", err, out.String())
+	}
+	if repo.remotes["origin"]["v0.1.1"] {
+		t.Error("Expected undo to delete v0.1.1 from origin")
+	}
+	if _, ok := repo.tags["v0.1.1"]; ok {
+		t.Error("Expected undo to delete tag v0.1.1")
+	}
+}
+
+func TestAppFakeList(t *testing.T) {
+	app, repo, out := fakeSetup(t, "")
+	repo.tag("v0.2.0", "Second")
+	repo.tag("api/v1.0.0", "A module")
+
+	if err := app.WithJSON(true).List(t.Context(), 10); err != nil {
+		t.Fatalf("app.List returned an error: %v", err)
+	}
+
+	var tags []struct {
+		Name    string `json:"name"`
+		Commit  string `json:"commit"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &tags); err != nil {
+		t.Fatalf("Could not parse JSON output %q: %v", out.String(), err)
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if want := []string{"v0.2.0", "v0.1.0"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Wrong tags listed: got %v, wanted %v", names, want)
+	}
+	if tags[0].Commit != repo.head().hash || tags[0].Message != "Second" {
+		t.Errorf("Wrong detail for v0.2.0: %#v", tags[0])
+	}
+}
@@ -0,0 +1,487 @@
+package app //nolint: testpackage // We need access to some internals
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.followtheprocess.codes/semver"
+	"go.followtheprocess.codes/tag/git"
+)
+
+// fakeCommit is a commit in a fakeRepo, with a snapshot of every file in it.
+type fakeCommit struct {
+	files   map[string]string // Contents by path, relative to the repo's directory
+	hash    string
+	message string
+	signed  bool
+}
+
+// fakeTag is a tag in a fakeRepo.
+type fakeTag struct {
+	date    time.Time
+	commit  string
+	message string
+	signed  bool
+}
+
+// fakeRepo is an in-memory [git.Repo] so App can be tested without git.
+//
+// The App still reads and writes real files under dir, the fake reads them back
+// when staging and checks them against HEAD for IsDirty. Everything else (commits,
+// tags, the index and remotes) only lives in memory.
+type fakeRepo struct {
+	tags    map[string]fakeTag
+	remotes map[string]map[string]bool // Tags pushed to each remote
+	staged  map[string]string          // The index, nil if nothing has been staged since the last commit
+	dir     string
+	branch  string
+	commits []fakeCommit // Oldest first, HEAD is the last one
+	added   []string     // The paths passed to the last Add
+}
+
+var _ git.Repo = (*fakeRepo)(nil)
+
+// newFakeRepo returns a fakeRepo on branch main for the files under dir, with
+// an initial commit of everything that's there now.
+func newFakeRepo(dir string) (*fakeRepo, error) {
+	f := &fakeRepo{
+		dir:     dir,
+		branch:  "main",
+		tags:    make(map[string]fakeTag),
+		remotes: make(map[string]map[string]bool),
+	}
+	files, err := f.worktree()
+	if err != nil {
+		return nil, err
+	}
+	f.commit("Initial commit", files)
+	return f, nil
+}
+
+// commit adds a commit of files on top of HEAD, returning its hash.
+func (f *fakeRepo) commit(message string, files map[string]string) string {
+	hash := fmt.Sprintf("%040x", len(f.commits)+1)
+	f.commits = append(f.commits, fakeCommit{hash: hash, message: message, files: files})
+	return hash
+}
+
+// tag tags HEAD as name, as if it was done with git tag -a.
+func (f *fakeRepo) tag(name, message string) {
+	f.tags[name] = fakeTag{commit: f.head().hash, message: message, date: time.Now()}
+}
+
+// head returns the commit HEAD points to.
+func (f *fakeRepo) head() fakeCommit {
+	return f.commits[len(f.commits)-1]
+}
+
+// worktree reads every file under dir.
+func (f *fakeRepo) worktree() (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(f.dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(contents)
+		return nil
+	})
+	return files, err
+}
+
+// resolve returns the index in commits of the commit ref points to, refs may be
+// a hash, a tag, HEAD or the branch, optionally followed by ~<n>.
+func (f *fakeRepo) resolve(ref string) (int, error) {
+	base, back, _ := strings.Cut(ref, "~")
+	n := 0
+	if back != "" {
+		var err error
+		if n, err = strconv.Atoi(back); err != nil {
+			return 0, fmt.Errorf("could not resolve %s to a commit", ref)
+		}
+	}
+
+	hash := base
+	if tag, ok := f.tags[base]; ok {
+		hash = tag.commit
+	}
+	if base == "HEAD" || base == f.branch {
+		hash = f.head().hash
+	}
+
+	i := slices.IndexFunc(f.commits, func(c fakeCommit) bool { return c.hash == hash })
+	if i < 0 || i-n < 0 {
+		return 0, fmt.Errorf("could not resolve %s to a commit", ref)
+	}
+	return i - n, nil
+}
+
+// versionTags returns the tags of the form <prefix>v<version> in descending version order.
+func (f *fakeRepo) versionTags(prefix string) []string {
+	var names []string
+	versions := make(map[string]semver.Version)
+	for name := range f.tags {
+		rest, ok := strings.CutPrefix(name, prefix+"v")
+		if !ok || rest == "" || rest[0] < '0' || rest[0] > '9' {
+			continue
+		}
+		version, err := semver.Parse(rest)
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+		versions[name] = version
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		x, y := versions[a], versions[b]
+		return -cmp.Or(
+			cmp.Compare(x.Major, y.Major),
+			cmp.Compare(x.Minor, y.Minor),
+			cmp.Compare(x.Patch, y.Patch),
+			comparePrerelease(x.Prerelease, y.Prerelease),
+		)
+	})
+	return names
+}
+
+// IsRepo reports whether the repo exists, a fake one always does.
+func (f *fakeRepo) IsRepo(context.Context) bool {
+	return true
+}
+
+// Branch returns the name of the current branch.
+func (f *fakeRepo) Branch(context.Context) (string, error) {
+	return f.branch, nil
+}
+
+// IsDirty reports whether anything is staged or any file under dir differs from HEAD.
+func (f *fakeRepo) IsDirty(context.Context) (bool, error) {
+	if f.staged != nil {
+		return true, nil
+	}
+	files, err := f.worktree()
+	if err != nil {
+		return false, err
+	}
+	return !maps.Equal(files, f.head().files), nil
+}
+
+// ListFiles lists every file under dir.
+func (f *fakeRepo) ListFiles(context.Context) ([]string, error) {
+	files, err := f.worktree()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(files)), nil
+}
+
+// ListTags lists the version tags in descending order, as for [git.Exec.ListTags].
+func (f *fakeRepo) ListTags(_ context.Context, prefix string, limit int) (string, bool, error) {
+	names := f.versionTags(prefix)
+	if len(names) == 0 {
+		return "", false, git.ErrNoTagsFound
+	}
+	// Cut off the same way as git's output, which ends in a newline
+	lines := append(names, "")
+	if len(lines) > limit {
+		return strings.Join(lines[:limit], "\n"), true, nil
+	}
+	return strings.Join(lines, "\n"), false, nil
+}
+
+// Tags returns the detail of the version tags in descending order.
+func (f *fakeRepo) Tags(ctx context.Context, prefix string) ([]git.TagInfo, error) {
+	names := f.versionTags(prefix)
+	tags := make([]git.TagInfo, 0, len(names))
+	for _, name := range names {
+		tag, err := f.Tag(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// Tag returns the detail of a single tag.
+func (f *fakeRepo) Tag(_ context.Context, name string) (git.TagInfo, error) {
+	tag, ok := f.tags[name]
+	if !ok {
+		return git.TagInfo{}, fmt.Errorf("tag %s not found", name)
+	}
+	subject, _, _ := strings.Cut(tag.message, "\n")
+	return git.TagInfo{Date: tag.date, Name: name, Commit: tag.commit, Message: subject}, nil
+}
+
+// LatestTag returns the highest version tag on the nearest tagged commit to HEAD.
+func (f *fakeRepo) LatestTag(_ context.Context, prefix string) (string, error) {
+	names := f.versionTags(prefix)
+	for i := len(f.commits) - 1; i >= 0; i-- {
+		for _, name := range names {
+			if f.tags[name].commit == f.commits[i].hash {
+				return name, nil
+			}
+		}
+	}
+	return "", git.ErrNoTagsFound
+}
+
+// TagMessage returns the message of a tag.
+func (f *fakeRepo) TagMessage(_ context.Context, name string) (string, error) {
+	tag, ok := f.tags[name]
+	if !ok {
+		return "", fmt.Errorf("could not get message of tag %s", name)
+	}
+	return tag.message, nil
+}
+
+// CreateTag tags HEAD.
+func (f *fakeRepo) CreateTag(_ context.Context, name, message string) (string, error) {
+	if _, ok := f.tags[name]; ok {
+		return fmt.Sprintf("fatal: tag '%s' already exists", name), errors.New("exit status 128")
+	}
+	f.tag(name, cmp.Or(message, name))
+	return "", nil
+}
+
+// SignedTag tags HEAD, marking the tag as signed.
+func (f *fakeRepo) SignedTag(ctx context.Context, name, message string, _ git.Signing) (string, error) {
+	out, err := f.CreateTag(ctx, name, message)
+	if err != nil {
+		return out, err
+	}
+	tag := f.tags[name]
+	tag.signed = true
+	f.tags[name] = tag
+	return "", nil
+}
+
+// DeleteTag deletes a tag.
+func (f *fakeRepo) DeleteTag(_ context.Context, name string) (string, error) {
+	tag, ok := f.tags[name]
+	if !ok {
+		return fmt.Sprintf("error: tag '%s' not found.", name), errors.New("exit status 1")
+	}
+	delete(f.tags, name)
+	return fmt.Sprintf("Deleted tag '%s' (was %.7s)\n", name, tag.commit), nil
+}
+
+// VerifyTag checks a tag was signed.
+func (f *fakeRepo) VerifyTag(_ context.Context, name string) (git.Verification, error) {
+	if !f.tags[name].signed {
+		return git.Verification{}, fmt.Errorf("could not verify tag %s: no signature found", name)
+	}
+	return git.Verification{Signer: "Tag Test <tagtest@gmail.com>"}, nil
+}
+
+// RevParse returns the hash of the commit ref points to.
+func (f *fakeRepo) RevParse(_ context.Context, ref string) (string, error) {
+	i, err := f.resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	return f.commits[i].hash, nil
+}
+
+// CommitsSince returns the commits after ref up to HEAD, newest first, only
+// those changing a file under one of paths if any are given.
+func (f *fakeRepo) CommitsSince(_ context.Context, ref string, paths ...string) ([]git.LogEntry, error) {
+	start := 0
+	if ref != "" {
+		i, err := f.resolve(ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not get commits since %q: %w", ref, err)
+		}
+		start = i + 1
+	}
+
+	var entries []git.LogEntry
+	for i := len(f.commits) - 1; i >= start; i-- {
+		if len(paths) != 0 && !f.touches(i, paths) {
+			continue
+		}
+		entries = append(entries, git.LogEntry{Hash: f.commits[i].hash, Message: f.commits[i].message})
+	}
+	return entries, nil
+}
+
+// touches reports whether the commit at i changed any file under paths.
+func (f *fakeRepo) touches(i int, paths []string) bool {
+	var parent map[string]string
+	if i > 0 {
+		parent = f.commits[i-1].files
+	}
+	files := f.commits[i].files
+	for _, name := range slices.Concat(slices.Collect(maps.Keys(files)), slices.Collect(maps.Keys(parent))) {
+		old, inParent := parent[name]
+		current, inCommit := files[name]
+		if old == current && inParent == inCommit {
+			continue
+		}
+		for _, path := range paths {
+			if name == path || strings.HasPrefix(name, strings.TrimSuffix(path, "/")+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CommitMessage returns the message of the commit ref points to.
+func (f *fakeRepo) CommitMessage(_ context.Context, ref string) (string, error) {
+	i, err := f.resolve(ref)
+	if err != nil {
+		return "", fmt.Errorf("could not get commit message of %s: %w", ref, err)
+	}
+	return f.commits[i].message, nil
+}
+
+// Show returns the contents of the file at path as of the commit ref points to.
+func (f *fakeRepo) Show(_ context.Context, ref, path string) ([]byte, error) {
+	i, err := f.resolve(ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not show %s at %s: %w", path, ref, err)
+	}
+	contents, ok := f.commits[i].files[path]
+	if !ok {
+		return nil, fmt.Errorf("could not show %s at %s: no such file", path, ref)
+	}
+	return []byte(contents), nil
+}
+
+// Add stages only paths, exactly as they are under dir, so a file the App wrote
+// but didn't pass in is left out of the commit.
+func (f *fakeRepo) Add(_ context.Context, paths ...string) error {
+	f.added = paths
+	if f.staged == nil {
+		f.staged = maps.Clone(f.head().files)
+	}
+	for _, path := range paths {
+		contents, err := os.ReadFile(filepath.Join(f.dir, path))
+		if errors.Is(err, fs.ErrNotExist) {
+			delete(f.staged, path)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		f.staged[path] = string(contents)
+	}
+	return nil
+}
+
+// Commit commits whatever was staged.
+func (f *fakeRepo) Commit(_ context.Context, message string) (string, error) {
+	if f.staged == nil || maps.Equal(f.staged, f.head().files) {
+		return "nothing to commit, working tree clean", errors.New("exit status 1")
+	}
+	hash := f.commit(message, f.staged)
+	f.staged = nil
+	subject, _, _ := strings.Cut(message, "\n")
+	return fmt.Sprintf("[%s %.7s] %s\n", f.branch, hash, subject), nil
+}
+
+// SignedCommit commits whatever was staged, marking the commit as signed.
+func (f *fakeRepo) SignedCommit(ctx context.Context, message string, _ git.Signing) (string, error) {
+	out, err := f.Commit(ctx, message)
+	if err != nil {
+		return out, err
+	}
+	f.commits[len(f.commits)-1].signed = true
+	return out, nil
+}
+
+// Reset moves HEAD back to ref, putting the files it changed back under dir.
+func (f *fakeRepo) Reset(_ context.Context, ref string) (string, error) {
+	i, err := f.resolve(ref)
+	if err != nil {
+		return err.Error(), err
+	}
+	current, target := f.head().files, f.commits[i].files
+	for name := range current {
+		if _, ok := target[name]; !ok {
+			if err := os.Remove(filepath.Join(f.dir, name)); err != nil {
+				return err.Error(), err
+			}
+		}
+	}
+	for name, contents := range target {
+		if current[name] != contents {
+			if err := os.WriteFile(filepath.Join(f.dir, name), []byte(contents), filePermissions); err != nil {
+				return err.Error(), err
+			}
+		}
+	}
+	f.commits = f.commits[:i+1]
+	f.staged = nil
+	return "", nil
+}
+
+// ResetMixed moves HEAD back to ref and unstages everything, leaving the files alone.
+func (f *fakeRepo) ResetMixed(_ context.Context, ref string) (string, error) {
+	i, err := f.resolve(ref)
+	if err != nil {
+		return err.Error(), err
+	}
+	f.commits = f.commits[:i+1]
+	f.staged = nil
+	return "", nil
+}
+
+// Push records that the tags in refs were pushed to remote.
+func (f *fakeRepo) Push(_ context.Context, remote string, refs ...string) (string, error) {
+	pushed, ok := f.remotes[remote]
+	if !ok {
+		return fmt.Sprintf("fatal: '%s' does not appear to be a git repository", remote), errors.New("exit status 128")
+	}
+	for _, ref := range refs {
+		if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+			pushed[tag] = true
+		}
+	}
+	return "", nil
+}
+
+// DefaultRemote returns origin if there is one, otherwise an empty string.
+func (f *fakeRepo) DefaultRemote(context.Context) (string, error) {
+	if _, ok := f.remotes["origin"]; ok {
+		return "origin", nil
+	}
+	return "", nil
+}
+
+// RemoteHasTag reports whether tag was pushed to remote.
+func (f *fakeRepo) RemoteHasTag(_ context.Context, remote, tag string) (bool, error) {
+	pushed, ok := f.remotes[remote]
+	if !ok {
+		return false, fmt.Errorf("could not list tags on %s", remote)
+	}
+	return pushed[tag], nil
+}
+
+// DeleteRemoteTag deletes tag from remote.
+func (f *fakeRepo) DeleteRemoteTag(_ context.Context, remote, tag string) (string, error) {
+	pushed, ok := f.remotes[remote]
+	if !ok || !pushed[tag] {
+		return fmt.Sprintf("error: unable to delete '%s': remote ref does not exist", tag), errors.New("exit status 1")
+	}
+	delete(pushed, tag)
+	return "", nil
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// isGlob reports whether a [[file]] path is a glob pattern rather than a plain path.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// expandPaths is a helper that expands a [[file]] path into the files it refers to.
+//
+// A plain path is returned as is, a glob pattern (which may use "**" to match any
+// number of directories) is matched against all the files git knows about, so anything
+// in .gitignore is skipped.
+func (a App) expandPaths(ctx context.Context, pattern string) ([]string, error) {
+	if !isGlob(pattern) {
+		return []string{pattern}, nil
+	}
+
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	if err := validateGlob(pattern); err != nil {
+		return nil, err
+	}
+
+	files, err := a.Repo.ListFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, file := range files {
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(file, "/")) {
+			matches = append(matches, filepath.FromSlash(file))
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+
+	return matches, nil
+}
+
+// validateGlob checks each segment of a glob pattern is valid.
+func validateGlob(pattern string) error {
+	for segment := range strings.SplitSeq(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("bad glob pattern %s: %w", pattern, err)
+		}
+		if strings.Contains(segment, "**") && segment != "**" {
+			return fmt.Errorf("bad glob pattern %s: %w", pattern, errors.New("** must be a whole path segment"))
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether the slash separated segments of name match those of
+// pattern, where a "**" segment matches zero or more whole segments.
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) != 0 {
+		if pattern[0] == "**" {
+			for i := range len(name) + 1 {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		// Patterns are validated up front so there's no error to handle here
+		if ok, _ := path.Match(pattern[0], name[0]); !ok { //nolint: errcheck // See above
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.followtheprocess.codes/semver"
+	"go.followtheprocess.codes/tag/git"
+	"go.followtheprocess.codes/tag/hooks"
+)
+
+// tagJSON is the machine readable form of a tag, as output by list and latest.
+type tagJSON struct {
+	Date    time.Time    `json:"date"`
+	Version *versionJSON `json:"version"` // nil if the tag isn't semver
+	Name    string       `json:"name"`
+	Commit  string       `json:"commit"`
+	Message string       `json:"message"`
+}
+
+// versionJSON is the machine readable form of a semantic version.
+type versionJSON struct {
+	Prerelease string `json:"prerelease,omitempty"`
+	Build      string `json:"build,omitempty"`
+	Major      uint64 `json:"major"`
+	Minor      uint64 `json:"minor"`
+	Patch      uint64 `json:"patch"`
+}
+
+// bumpResult is the machine readable record of a bump, it's filled in as the bump
+// goes and output once it's done.
+//
+// All the methods are safe to call on a nil bumpResult, they do nothing.
+type bumpResult struct {
+	Current string            `json:"current"`
+	Next    string            `json:"next"`
+	Tag     string            `json:"tag"`
+	Commit  string            `json:"commit,omitempty"` // The bump commit, empty if there wasn't one
+	Files   []fileJSON        `json:"files"`
+	Hooks   []string          `json:"hooks"` // The hook stages that ran e.g. "pre-commit"
+	Pushed  bool              `json:"pushed"`
+	Capture map[string]string `json:"captured,omitempty"` // Output captured by hooks, by name
+	Remotes []string          `json:"remotes,omitempty"`  // Where the tag was pushed, empty if it wasn't
+	DryRun  bool              `json:"dry_run"`
+}
+
+// fileJSON is the machine readable record of a file changed by a bump.
+type fileJSON struct {
+	Path         string `json:"path"`
+	Replacements int    `json:"replacements"`
+}
+
+// file records that path was (or would have been) changed.
+func (r *bumpResult) file(path string, replacements int) {
+	if r == nil {
+		return
+	}
+	r.Files = append(r.Files, fileJSON{Path: path, Replacements: replacements})
+}
+
+// hook records that the hook for stage was (or would have been) run.
+func (r *bumpResult) hook(stage hooks.HookStage) {
+	if r == nil {
+		return
+	}
+	r.Hooks = append(r.Hooks, stage.Key())
+}
+
+// capture records the output captured by a hook under name.
+func (r *bumpResult) capture(name, output string) {
+	if r == nil {
+		return
+	}
+	if r.Capture == nil {
+		r.Capture = make(map[string]string)
+	}
+	r.Capture[name] = output
+}
+
+// newTagJSON converts a git tag into its machine readable form, prefix is stripped
+// from the name before parsing the version.
+func newTagJSON(tag git.TagInfo, prefix string) tagJSON {
+	out := tagJSON{
+		Name:    tag.Name,
+		Commit:  tag.Commit,
+		Date:    tag.Date,
+		Message: tag.Message,
+	}
+
+	version, err := semver.Parse(strings.TrimPrefix(tag.Name, prefix))
+	if err == nil {
+		out.Version = &versionJSON{
+			Major:      version.Major,
+			Minor:      version.Minor,
+			Patch:      version.Patch,
+			Prerelease: version.Prerelease,
+			Build:      version.Build,
+		}
+	}
+
+	return out
+}
+
+// writeJSON is a helper that writes v as indented JSON to the App's JSON output.
+func (a App) writeJSON(v any) error {
+	encoder := json.NewEncoder(a.jsonOut)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("could not write JSON output: %w", err)
+	}
+	return nil
+}
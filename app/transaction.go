@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.followtheprocess.codes/msg"
+	"go.followtheprocess.codes/tag/git"
+)
+
+// transaction keeps track of everything a bump changes so that if any step fails,
+// the repo can be put back exactly as it was.
+type transaction struct {
+	repo      git.Repo          // The repo the bump is happening in
+	dir       string            // The directory the paths are relative to
+	originals map[string][]byte // Original contents of every file written, nil if it didn't exist
+	paths     []string          // The order files were first touched in, so reports are stable
+	head      string            // HEAD before any changes were staged, empty if nothing was staged
+	tag       string            // The tag that was created, empty if there wasn't one
+	commit    bool              // Whether the bump commit was made
+}
+
+// newTransaction returns a new, empty transaction in repo, for files relative to dir.
+func newTransaction(repo git.Repo, dir string) *transaction {
+	return &transaction{repo: repo, dir: dir, originals: make(map[string][]byte)}
+}
+
+// snapshot records the current contents of the file at path, if this is the
+// first time it has been touched.
+func (t *transaction) snapshot(path string) error {
+	if _, ok := t.originals[path]; ok {
+		return nil
+	}
+
+	contents, err := os.ReadFile(filepath.Join(t.dir, path))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("could not snapshot %s: %w", path, err)
+		}
+		contents = nil
+	} else if contents == nil {
+		// Distinguish an empty file from one that didn't exist
+		contents = []byte{}
+	}
+
+	t.originals[path] = contents
+	t.paths = append(t.paths, path)
+	return nil
+}
+
+// write snapshots then writes the file at path.
+func (t *transaction) write(path string, contents []byte) error {
+	if err := t.snapshot(path); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.dir, path), contents, filePermissions)
+}
+
+// stage stages every file written so far on top of head, ready to commit.
+func (t *transaction) stage(ctx context.Context, head string) error {
+	if err := t.repo.Add(ctx, t.paths...); err != nil {
+		return err
+	}
+	t.head = head
+	return nil
+}
+
+// committed records that the staged changes were committed.
+func (t *transaction) committed() {
+	t.commit = true
+}
+
+// empty reports whether the transaction has nothing to roll back.
+func (t *transaction) empty() bool {
+	return len(t.paths) == 0 && t.head == "" && t.tag == ""
+}
+
+// tagged records that tag was created.
+func (t *transaction) tagged(tag string) {
+	t.tag = tag
+}
+
+// rollback undoes everything recorded in the transaction, in reverse order, reporting
+// each thing it reverts to w.
+//
+// It carries on past any failures so as much as possible is put back, and returns
+// all of them.
+func (t *transaction) rollback(ctx context.Context, w io.Writer) error {
+	var errs []error
+
+	if t.tag != "" {
+		if out, err := t.repo.DeleteTag(ctx, t.tag); err != nil {
+			errs = append(errs, fmt.Errorf("could not delete tag %s: %s", t.tag, out))
+		} else {
+			msg.Fwarn(w, "Deleted tag %s", t.tag)
+		}
+	}
+
+	if t.head != "" {
+		// Only the index and branch, the files are put back from the snapshots below
+		if out, err := t.repo.ResetMixed(ctx, t.head); err != nil {
+			errs = append(errs, fmt.Errorf("could not reset to %.7s: %s", t.head, out))
+		} else if t.commit {
+			msg.Fwarn(w, "Reset the bump commit")
+		} else {
+			msg.Fwarn(w, "Unstaged changes")
+		}
+	}
+
+	for i := len(t.paths) - 1; i >= 0; i-- {
+		path := t.paths[i]
+		original := t.originals[path]
+		if original == nil {
+			if err := os.Remove(filepath.Join(t.dir, path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("could not remove %s: %w", path, err))
+				continue
+			}
+			msg.Fwarn(w, "Removed %s", path)
+			continue
+		}
+
+		if err := os.WriteFile(filepath.Join(t.dir, path), original, filePermissions); err != nil {
+			errs = append(errs, fmt.Errorf("could not restore %s: %w", path, err))
+			continue
+		}
+		msg.Fwarn(w, "Restored %s", path)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,177 @@
+// Package changelog implements tag's changelog generation, grouping the
+// commits since the previous tag by their conventional commit type and
+// rendering them into a new changelog section.
+package changelog
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"go.followtheprocess.codes/tag/conventional"
+	"go.followtheprocess.codes/tag/git"
+)
+
+// DefaultTemplate is the template used to render a changelog section if
+// one is not configured.
+const DefaultTemplate = `## {{.Version}} - {{.Date}}
+{{range .Groups}}
+### {{.Title}}
+
+{{range .Entries}}- {{if .Scope}}**{{.Scope}}:** {{end}}{{.Description}} ({{.Short}})
+{{end}}{{end}}`
+
+// groups is the order and title of the known commit type groups, anything
+// not in here ends up in "Miscellaneous".
+var groups = []struct {
+	typ   string
+	title string
+}{
+	{typ: "feat", title: "Features"},
+	{typ: "fix", title: "Bug Fixes"},
+	{typ: "perf", title: "Performance"},
+	{typ: "refactor", title: "Refactors"},
+	{typ: "docs", title: "Documentation"},
+}
+
+const (
+	breakingTitle = "Breaking Changes"
+	otherTitle    = "Miscellaneous"
+	shortHashLen  = 7
+)
+
+// Section is the data available to a changelog template.
+type Section struct {
+	Version  string  // The new version e.g. "1.4.0"
+	Previous string  // The previous version e.g. "1.3.2"
+	Tag      string  // The new tag e.g. "v1.4.0"
+	Date     string  // The date of the release, formatted as YYYY-MM-DD
+	Groups   []Group // The commits grouped by type, empty groups are omitted
+}
+
+// Group is a set of changelog entries sharing a type, e.g. all the features.
+type Group struct {
+	Title   string  // The heading for the group e.g. "Bug Fixes"
+	Entries []Entry // The entries in the group, newest first
+}
+
+// Entry is a single line in the changelog, made from a conventional commit.
+type Entry struct {
+	Hash        string // The full commit hash
+	Type        string // The conventional commit type e.g. "feat"
+	Scope       string // The optional scope
+	Description string // The commit description
+	Breaking    bool   // Whether the commit is a breaking change
+}
+
+// Short returns the abbreviated commit hash.
+func (e Entry) Short() string {
+	if len(e.Hash) <= shortHashLen {
+		return e.Hash
+	}
+	return e.Hash[:shortHashLen]
+}
+
+// GroupCommits sorts commits into changelog groups by conventional commit type,
+// commits that are not conventional commits are left out.
+//
+// Breaking changes get their own group at the top regardless of type.
+func GroupCommits(commits []git.LogEntry) []Group {
+	byTitle := make(map[string][]Entry)
+	for _, commit := range commits {
+		parsed, err := conventional.Parse(commit.Message)
+		if err != nil {
+			continue
+		}
+
+		entry := Entry{
+			Hash:        commit.Hash,
+			Type:        parsed.Type,
+			Scope:       parsed.Scope,
+			Description: parsed.Description,
+			Breaking:    parsed.Breaking,
+		}
+
+		title := otherTitle
+		for _, group := range groups {
+			if group.typ == parsed.Type {
+				title = group.title
+				break
+			}
+		}
+		if parsed.Breaking {
+			title = breakingTitle
+		}
+
+		byTitle[title] = append(byTitle[title], entry)
+	}
+
+	order := []string{breakingTitle}
+	for _, group := range groups {
+		order = append(order, group.title)
+	}
+	order = append(order, otherTitle)
+
+	var result []Group
+	for _, title := range order {
+		if entries := byTitle[title]; len(entries) != 0 {
+			result = append(result, Group{Title: title, Entries: entries})
+		}
+	}
+
+	return result
+}
+
+// Render renders section with the given template text, if text is empty
+// then [DefaultTemplate] is used.
+func Render(text string, section Section) (string, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+
+	tmpl, err := template.New("changelog").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("could not parse changelog.template: %w", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, section); err != nil {
+		return "", fmt.Errorf("could not execute changelog.template: %w", err)
+	}
+
+	return out.String(), nil
+}
+
+// Prepend inserts a rendered section at the top of an existing changelog.
+//
+// If the changelog starts with a top level heading (e.g. "# Changelog"), along with
+// any text directly below it, the section goes after that so the title stays at the top.
+func Prepend(contents []byte, section string) []byte {
+	section = string(bytes.TrimSpace([]byte(section))) + "\n"
+
+	if len(bytes.TrimSpace(contents)) == 0 {
+		return []byte(section)
+	}
+
+	if !bytes.HasPrefix(contents, []byte("# ")) {
+		return append([]byte(section+"\n"), contents...)
+	}
+
+	// Find the first level 2 (or deeper) heading after the title, that's
+	// where the previous release starts
+	index := bytes.Index(contents, []byte("\n##"))
+	if index == -1 {
+		return append(append(bytes.TrimRight(contents, "\n"), "\n\n"...), section...)
+	}
+
+	head := bytes.TrimRight(contents[:index], "\n")
+	tail := contents[index+1:]
+
+	out := make([]byte, 0, len(contents)+len(section)+2)
+	out = append(out, head...)
+	out = append(out, "\n\n"...)
+	out = append(out, section...)
+	out = append(out, '\n')
+	out = append(out, tail...)
+	return out
+}
@@ -0,0 +1,316 @@
+package changelog_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.followtheprocess.codes/tag/changelog"
+	"go.followtheprocess.codes/tag/git"
+)
+
+func TestGroupCommits(t *testing.T) {
+	commits := []git.LogEntry{
+		{Hash: "1111111111", Message: "feat(cli): Add a thing"},
+		{Hash: "2222222222", Message: "Bump version 0.1.0 -> 0.2.0"},
+		{Hash: "3333333333", Message: "fix: Fix a thing"},
+		{Hash: "4444444444", Message: "chore: Tidy up"},
+		{Hash: "5555555555", Message: "feat!: Change everything"},
+		{Hash: "6666666666", Message: "feat: Add another thing"},
+	}
+
+	want := []changelog.Group{
+		{
+			Title: "Breaking Changes",
+			Entries: []changelog.Entry{
+				{Hash: "5555555555", Type: "feat", Description: "Change everything", Breaking: true},
+			},
+		},
+		{
+			Title: "Features",
+			Entries: []changelog.Entry{
+				{Hash: "1111111111", Type: "feat", Scope: "cli", Description: "Add a thing"},
+				{Hash: "6666666666", Type: "feat", Description: "Add another thing"},
+			},
+		},
+		{
+			Title: "Bug Fixes",
+			Entries: []changelog.Entry{
+				{Hash: "3333333333", Type: "fix", Description: "Fix a thing"},
+			},
+		},
+		{
+			Title: "Miscellaneous",
+			Entries: []changelog.Entry{
+				{Hash: "4444444444", Type: "chore", Description: "Tidy up"},
+			},
+		},
+	}
+
+	got := changelog.GroupCommits(commits)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	section := changelog.Section{
+		Version:  "1.4.0",
+		Previous: "1.3.0",
+		Tag:      "v1.4.0",
+		Date:     "2026-10-17",
+		Groups: []changelog.Group{
+			{
+				Title: "Features",
+				Entries: []changelog.Entry{
+					{Hash: "1111111111", Type: "feat", Scope: "cli", Description: "Add a thing"},
+					{Hash: "6666666666", Type: "feat", Description: "Add another thing"},
+				},
+			},
+			{
+				Title: "Bug Fixes",
+				Entries: []changelog.Entry{
+					{Hash: "3333333333", Type: "fix", Description: "Fix a thing"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "default",
+			template: "",
+			want: `## 1.4.0 - 2026-10-17
+
+### Features
+
+- **cli:** Add a thing (1111111)
+- Add another thing (6666666)
+
+### Bug Fixes
+
+- Fix a thing (3333333)
+`,
+		},
+		{
+			name:     "custom",
+			template: "# {{.Tag}} (from {{.Previous}}){{range .Groups}} {{.Title}}: {{len .Entries}}{{end}}",
+			want:     "# v1.4.0 (from 1.3.0) Features: 2 Bug Fixes: 1",
+		},
+		{
+			name:     "bad template",
+			template: "{{.Version",
+			wantErr:  true,
+		},
+		{
+			name:     "bad field",
+			template: "{{.Missing}}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := changelog.Render(tt.template, section)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Got:\n%s\n\nWanted:\n%s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	section := "## 1.4.0\n\n- New thing\n"
+
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "empty",
+			contents: "",
+			want:     "## 1.4.0\n\n- New thing\n",
+		},
+		{
+			name:     "no title",
+			contents: "## 1.3.0\n\n- Old thing\n",
+			want:     "## 1.4.0\n\n- New thing\n\n## 1.3.0\n\n- Old thing\n",
+		},
+		{
+			name:     "title",
+			contents: "# Changelog\n\nAll notable changes.\n\n## 1.3.0\n\n- Old thing\n",
+			want:     "# Changelog\n\nAll notable changes.\n\n## 1.4.0\n\n- New thing\n\n## 1.3.0\n\n- Old thing\n",
+		},
+		{
+			name:     "title only",
+			contents: "# Changelog\n",
+			want:     "# Changelog\n\n## 1.4.0\n\n- New thing\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(changelog.Prepend([]byte(tt.contents), section))
+			if got != tt.want {
+				t.Errorf("Got:\n%q\n\nWanted:\n%q\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnreleased(t *testing.T) {
+	tests := []struct {
+		err      error
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "populated",
+			contents: "# Changelog\n\n## [Unreleased]\n\n### Added\n\n- A thing\n\n## [1.3.0] - 2026-01-01\n\n- Old\n",
+			want:     "\n### Added\n\n- A thing\n\n",
+		},
+		{
+			name:     "no brackets",
+			contents: "## Unreleased\n- A thing\n",
+			want:     "- A thing\n",
+		},
+		{
+			name:     "headings only",
+			contents: "## [Unreleased]\n\n### Added\n\n### Fixed\n\n## [1.3.0] - 2026-01-01\n",
+			err:      changelog.ErrEmptyUnreleased,
+		},
+		{
+			name:     "empty before links",
+			contents: "## [Unreleased]\n\n[Unreleased]: https://github.com/o/r/compare/v1.3.0...HEAD\n",
+			err:      changelog.ErrEmptyUnreleased,
+		},
+		{
+			name:     "missing",
+			contents: "# Changelog\n\n## [1.3.0] - 2026-01-01\n",
+			err:      changelog.ErrNoUnreleased,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := changelog.Unreleased([]byte(tt.contents))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, wanted %v", err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Got:\n%q\n\nWanted:\n%q\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromote(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "full",
+			contents: `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+### Added
+
+- A new thing
+
+## [1.3.0] - 2026-01-01
+
+### Fixed
+
+- An old thing
+
+[Unreleased]: https://github.com/o/r/compare/v1.3.0...HEAD
+[1.3.0]: https://github.com/o/r/compare/v1.2.0...v1.3.0
+`,
+			want: `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+## [1.4.0] - 2026-10-17
+
+### Added
+
+- A new thing
+
+## [1.3.0] - 2026-01-01
+
+### Fixed
+
+- An old thing
+
+[Unreleased]: https://github.com/o/r/compare/v1.4.0...HEAD
+[1.4.0]: https://github.com/o/r/compare/v1.3.0...v1.4.0
+[1.3.0]: https://github.com/o/r/compare/v1.2.0...v1.3.0
+`,
+		},
+		{
+			name: "only unreleased",
+			contents: `## [Unreleased]
+- A new thing
+
+[Unreleased]: https://github.com/o/r/compare/v1.3.0...HEAD
+`,
+			want: `## [Unreleased]
+
+## [1.4.0] - 2026-10-17
+
+- A new thing
+
+[Unreleased]: https://github.com/o/r/compare/v1.4.0...HEAD
+[1.4.0]: https://github.com/o/r/compare/v1.3.0...v1.4.0
+`,
+		},
+		{
+			name:     "no links",
+			contents: "## [Unreleased]\n\n- A new thing\n",
+			want:     "## [Unreleased]\n\n## [1.4.0] - 2026-10-17\n\n- A new thing\n",
+		},
+		{
+			name:     "empty",
+			contents: "## [Unreleased]\n\n## [1.3.0] - 2026-01-01\n",
+			want:     "## [Unreleased]\n\n## [1.4.0] - 2026-10-17\n\n## [1.3.0] - 2026-01-01\n",
+		},
+		{
+			name:     "missing",
+			contents: "# Changelog\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := changelog.Promote([]byte(tt.contents), "1.4.0", "v1.4.0", "2026-10-17")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("Got:\n%s\n\nWanted:\n%s\n", got, tt.want)
+			}
+		})
+	}
+}
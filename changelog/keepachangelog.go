@@ -0,0 +1,153 @@
+package changelog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The supported changelog styles.
+const (
+	StyleGenerate       = "generate"         // Generate sections from conventional commits, the default
+	StyleKeepAChangelog = "keep-a-changelog" // Promote a hand written Unreleased section, see https://keepachangelog.com
+)
+
+var (
+	// ErrNoUnreleased is returned when a Keep a Changelog file has no Unreleased section.
+	ErrNoUnreleased = errors.New("no Unreleased section found")
+
+	// ErrEmptyUnreleased is returned when the Unreleased section has nothing in it.
+	ErrEmptyUnreleased = errors.New("Unreleased section is empty")
+)
+
+var (
+	// unreleasedHeading matches "## [Unreleased]" or "## Unreleased".
+	unreleasedHeading = regexp.MustCompile(`(?im)^##[ \t]+\[?unreleased\]?[ \t]*$`)
+
+	// linkDefinition matches a markdown link reference definition e.g. "[1.2.0]: https://...".
+	linkDefinition = regexp.MustCompile(`^\[[^\]]+\]:\s`)
+
+	// unreleasedLink matches the Unreleased compare link, capturing everything up to the
+	// previous tag and the previous tag itself.
+	unreleasedLink = regexp.MustCompile(`(?im)^\[unreleased\]:[ \t]*(\S*/compare/)(\S+)\.\.\.HEAD[ \t]*$`)
+)
+
+// unreleased is the location of the Unreleased section in a changelog.
+type unreleased struct {
+	body    string // The contents of the section, without the heading
+	heading int    // Byte offset of the start of the heading
+	start   int    // Byte offset of the start of the body
+	end     int    // Byte offset of the end of the body
+}
+
+// Unreleased returns the contents of the Unreleased section of a Keep a Changelog
+// style changelog, returning [ErrNoUnreleased] if there isn't one and [ErrEmptyUnreleased]
+// if it has no entries (sub headings like "### Added" on their own don't count).
+func Unreleased(contents []byte) (string, error) {
+	section, err := findUnreleased(contents)
+	if err != nil {
+		return "", err
+	}
+
+	for line := range strings.Lines(section.body) {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return section.body, nil
+		}
+	}
+
+	return "", ErrEmptyUnreleased
+}
+
+// Promote moves everything under the Unreleased heading into a new section for
+// version, leaving a fresh empty Unreleased section at the top.
+//
+// If the changelog has an Unreleased compare link at the bottom, it is updated to
+// compare from tag, and a compare link for the new version is added below it.
+//
+// An empty Unreleased section is allowed here, callers wanting to prevent this should
+// check with [Unreleased] first.
+func Promote(contents []byte, version, tag, date string) ([]byte, error) {
+	section, err := findUnreleased(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	body := strings.Trim(section.body, "\n")
+	if body != "" {
+		body += "\n"
+	}
+
+	heading := fmt.Sprintf("## [%s] - %s\n", version, date)
+	if body != "" {
+		heading += "\n"
+	}
+
+	out := &bytes.Buffer{}
+	out.Write(contents[:section.heading])
+	out.WriteString("## [Unreleased]\n\n")
+	out.WriteString(heading)
+	out.WriteString(body)
+	if section.end < len(contents) {
+		out.WriteString("\n")
+	}
+	out.Write(contents[section.end:])
+
+	promoted := out.Bytes()
+
+	match := unreleasedLink.FindSubmatchIndex(promoted)
+	if match == nil {
+		// No links to update
+		return promoted, nil
+	}
+
+	base := string(promoted[match[2]:match[3]])
+	previous := string(promoted[match[4]:match[5]])
+	links := fmt.Sprintf("[Unreleased]: %s%s...HEAD\n[%s]: %s%s...%s", base, tag, version, base, previous, tag)
+
+	result := make([]byte, 0, len(promoted)+len(links))
+	result = append(result, promoted[:match[0]]...)
+	result = append(result, links...)
+	result = append(result, promoted[match[1]:]...)
+
+	return result, nil
+}
+
+// findUnreleased locates the Unreleased section in contents.
+func findUnreleased(contents []byte) (unreleased, error) {
+	loc := unreleasedHeading.FindIndex(contents)
+	if loc == nil {
+		return unreleased{}, ErrNoUnreleased
+	}
+
+	start := loc[1]
+	if start < len(contents) && contents[start] == '\n' {
+		start++
+	}
+
+	// The section ends at the next level 2 heading, or the link definitions
+	// at the bottom of the file, whichever comes first
+	end := start
+	for end < len(contents) {
+		lineEnd := bytes.IndexByte(contents[end:], '\n')
+		if lineEnd == -1 {
+			lineEnd = len(contents) - end
+		} else {
+			lineEnd++
+		}
+		line := contents[end : end+lineEnd]
+		if bytes.HasPrefix(line, []byte("## ")) || linkDefinition.Match(line) {
+			break
+		}
+		end += lineEnd
+	}
+
+	return unreleased{
+		heading: loc[0],
+		start:   start,
+		end:     end,
+		body:    string(contents[start:end]),
+	}, nil
+}
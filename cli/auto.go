@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/cli/flag"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	autoLong = `
+Reads the commit messages since the latest tag and applies the
+Conventional Commits rules (https://www.conventionalcommits.org)
+to pick the bump type:
+
+- A breaking change ("feat!: ..." or a "BREAKING CHANGE:" footer) is a major bump
+- A "feat: ..." commit is a minor bump
+- A "fix: ..." commit is a patch bump
+
+Commits that don't follow the spec are ignored. If none of the commits
+warrant a release, tag exits with an error and does nothing.
+
+If the "-d/--dry-run" flag is used, tag will also print the commits
+that drove the decision.
+
+You may also push the tag to any configured remote
+with the "-p/--push" flag, "--remote <name>" picks which.
+
+You will be prompted for confirmation before bumping, this
+can be bypassed by passing the "-f/--force" flag.
+`
+)
+
+// buildAuto builds and returns the auto subcommand.
+func (g *globalFlags) buildAuto() (*cli.Command, error) {
+	var (
+		options app.BumpOptions
+		module  string
+	)
+	cmd, err := cli.New(
+		"auto",
+		cli.Short("Infer the bump type from conventional commits and issue a new tag"),
+		cli.Long(autoLong),
+		cli.Example("Bump based on the commits since the last tag", "tag auto"),
+		cli.Example("See what tag would do and why", "tag auto --dry-run"),
+		cli.Example("Use in CI", "tag auto --push --force"),
+		cli.Flag(&options.Push, "push", 'p', "Push the tag to the remote"),
+		cli.Flag(&options.Remote, "remote", flag.NoShortHand, "Push to this remote instead of the configured one"),
+		cli.Flag(&options.Force, "force", 'f', "Bypass confirmation prompt"),
+		cli.Flag(&options.DryRun, "dry-run", 'd', "Print what would have happened"),
+		cli.Flag(&options.AllowEmptyChangelog, "allow-empty-changelog", flag.NoShortHand, "Allow an empty Unreleased changelog section"),
+		cli.Flag(&options.Pre, "pre", flag.NoShortHand, "Issue a pre-release with this label e.g. rc"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			tag = tag.WithJSON(g.json)
+			return tag.Auto(ctx, options)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
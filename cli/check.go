@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	checkLong = `
+Checks that every file in the config file contains the current version,
+and that the latest tag is the tag for that version.
+
+Nothing is changed, tag just reports on each file and exits non-zero if
+anything doesn't match, which makes it a handy CI check to catch version
+drift before anyone tries to bump.
+`
+)
+
+// buildCheck builds and returns the check subcommand.
+func (g *globalFlags) buildCheck() (*cli.Command, error) {
+	var module string
+	cmd, err := cli.New(
+		"check",
+		cli.Short("Check all configured files match the current version"),
+		cli.Long(checkLong),
+		cli.Example("Check for version drift", "tag check"),
+		cli.Example("Check a module in a monorepo", "tag check --module api"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			return tag.Check(ctx)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
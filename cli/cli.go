@@ -1,7 +1,16 @@
 // Package cli implements tags command line interface.
 package cli
 
-import "go.followtheprocess.codes/cli"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/cli/flag"
+)
 
 // These are all set at compile time.
 var (
@@ -10,24 +19,99 @@ var (
 	buildDate = "unknown"
 )
 
+// globalFlags are the flags shared by every command.
+//
+// The cli library has no persistent flags, so they're declared once on the root
+// command and taken out of the arguments before the subcommand parses the rest,
+// which means they can go anywhere e.g. "tag --json list" or "tag list --json".
+type globalFlags struct {
+	config string // Use this config file rather than searching for .tag.toml
+	json   bool   // Output JSON rather than text, for the commands that can
+}
+
+// parse sets g from any global flags in args, returning the rest of args untouched.
+func (g *globalFlags) parse(args []string) ([]string, error) {
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "--":
+			// Everything after the terminator belongs to the command
+			return append(rest, args[i:]...), nil
+		case "--json":
+			if !hasValue {
+				g.json = true
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("flag --json received invalid value %q (expected bool)", value)
+			}
+			g.json = enabled
+		case "--config":
+			if !hasValue {
+				if i+1 == len(args) {
+					return nil, errors.New("flag --config needs an argument")
+				}
+				i++
+				value = args[i]
+			}
+			g.config = value
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, nil
+}
+
 // Build builds and returns the tag CLI.
 func Build() (*cli.Command, error) {
+	var global globalFlags
+	args, err := global.parse(os.Args[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse command flags: %w", err)
+	}
+
+	// Already parsed, these are here so they show up in --help
+	var (
+		asJSON     bool
+		configPath string
+	)
+
 	cmd, err := cli.New(
 		"tag",
 		cli.Short("The all in one semver management tool 🛠️"),
 		cli.Example("List tags in order", "tag list"),
 		cli.Example("Get latest tag", "tag latest"),
 		cli.Example("Bump a version (including content search and replace)", "tag {patch | minor | major}"),
+		cli.Example("Issue and promote pre-releases", "tag minor --pre rc && tag release"),
+		cli.Example("Bump based on conventional commits", "tag auto"),
+		cli.Example("Roll back the last bump", "tag undo"),
+		cli.Example("Check for version drift in CI", "tag check"),
+		cli.Example("Get the latest tag as JSON", "tag latest --json"),
+		cli.Example("Check the signature on the latest tag", "tag verify"),
 		cli.Version(version),
 		cli.Commit(commit),
 		cli.BuildDate(buildDate),
+		cli.OverrideArgs(args),
+		cli.Flag(&asJSON, "json", flag.NoShortHand, "Output JSON rather than text, for any command"),
+		cli.Flag(&configPath, "config", flag.NoShortHand, "Use this config file rather than searching for .tag.toml, for any command"),
 		cli.SubCommands(
+			global.buildAuto,
+			global.buildCheck,
+			global.buildConfig,
 			buildInit,
-			buildLatest,
-			buildList,
-			buildMajor,
-			buildMinor,
-			buildPatch,
+			global.buildLatest,
+			global.buildList,
+			global.buildMajor,
+			global.buildMinor,
+			global.buildNext,
+			global.buildPatch,
+			global.buildPre,
+			global.buildRelease,
+			global.buildUndo,
+			global.buildVerify,
 		),
 	)
 	if err != nil {
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	validateLong = `
+Checks the config file without changing anything: every key must be
+one tag knows about, every version must be valid semver, every template
+must parse and every [[file]] must exist.
+
+All problems are reported at once, with the line and column for any
+unknown keys. This validation also runs at the start of every other
+command, so a broken config is caught before tag starts bumping, though
+only bumping and check need every [[file]] to exist.
+`
+)
+
+// buildConfig builds and returns the config subcommand.
+func (g *globalFlags) buildConfig() (*cli.Command, error) {
+	cmd, err := cli.New(
+		"config",
+		cli.Short("Work with the tag config file"),
+		cli.Example("Validate the config file", "tag config validate"),
+		cli.SubCommands(g.buildConfigValidate),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+// buildConfigValidate builds and returns the config validate subcommand.
+func (g *globalFlags) buildConfigValidate() (*cli.Command, error) {
+	cmd, err := cli.New(
+		"validate",
+		cli.Short("Check the config file for mistakes"),
+		cli.Long(validateLong),
+		cli.Example("Validate the config file", "tag config validate"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			return tag.ValidateConfig(cwd)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
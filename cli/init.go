@@ -23,10 +23,9 @@ func buildInit() (*cli.Command, error) {
 				return err
 			}
 
-			tag, err := app.New(cwd, os.Stdout, os.Stderr)
-			if err != nil {
-				return err
-			}
+			// Not app.New, that validates the config file which may well be
+			// the broken one we're here to replace
+			tag := app.App{Stdout: os.Stdout, Stderr: os.Stderr}
 
 			return tag.Init(cwd, force)
 		}),
@@ -9,21 +9,28 @@ import (
 )
 
 // buildLatest builds and returns the latest subcommand.
-func buildLatest() (*cli.Command, error) {
+func (g *globalFlags) buildLatest() (*cli.Command, error) {
+	var module string
 	cmd, err := cli.New(
 		"latest",
 		cli.Short("Show latest semver tag"),
 		cli.Example("Show the latest", "tag latest"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
 		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
 			cwd, err := os.Getwd()
 			if err != nil {
 				return err
 			}
-			tag, err := app.New(cwd, os.Stdout, os.Stderr)
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
 			if err != nil {
 				return err
 			}
-			return tag.Latest()
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			tag = tag.WithJSON(g.json)
+			return tag.Latest(ctx)
 		}),
 	)
 	if err != nil {
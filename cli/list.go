@@ -13,24 +13,34 @@ const (
 )
 
 // buildList builds and returns the list subcommand.
-func buildList() (*cli.Command, error) {
-	var limit int
+func (g *globalFlags) buildList() (*cli.Command, error) {
+	var (
+		limit  int
+		module string
+	)
 	cmd, err := cli.New(
 		"list",
 		cli.Short("Show semver tags in order"),
 		cli.Example("Show all tags", "tag list"),
 		cli.Example("Limit to a max number", "tag list --limit 15"),
+		cli.Example("Show the tags of a module in a monorepo", "tag list --module api"),
 		cli.Flag(&limit, "limit", 'l', "Max number of tags to show", cli.FlagDefault(defaultLimit)),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
 		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
 			cwd, err := os.Getwd()
 			if err != nil {
 				return err
 			}
-			tag, err := app.New(cwd, os.Stdout, os.Stderr)
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
 			if err != nil {
 				return err
 			}
-			return tag.List(limit)
+			tag = tag.WithJSON(g.json)
+			return tag.List(ctx, limit)
 		}),
 	)
 	if err != nil {
@@ -5,13 +5,14 @@ import (
 	"os"
 
 	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/cli/flag"
 	"go.followtheprocess.codes/tag/app"
 )
 
 const (
 	minorLong = `
 You may also push the tag to any configured remote
-with the "-p/--push" flag.
+with the "-p/--push" flag, "--remote <name>" picks which.
 
 You will be prompted for confirmation before bumping, this
 can be bypassed by passing the "-f/--force" flag.
@@ -22,15 +23,18 @@ itself (e.g. "v1.2.4").
 If the "-d/--dry-run" flag is used, tag will simply print what would
 have happened, but not do anything. This is useful for checking you have
 set everything up correctly.
+
+Pass "--pre <label>" to issue a pre-release of the bumped version
+instead, e.g. "--pre rc" gives "v1.3.0-rc.1". The counter is worked
+out from the existing tags.
 `
 )
 
 // buildMinor builds and returns the minor subcommand.
-func buildMinor() (*cli.Command, error) {
+func (g *globalFlags) buildMinor() (*cli.Command, error) {
 	var (
-		push   bool
-		force  bool
-		dryRun bool
+		options app.BumpOptions
+		module  string
 	)
 	cmd, err := cli.New(
 		"minor",
@@ -39,19 +43,29 @@ func buildMinor() (*cli.Command, error) {
 		cli.Example("Bump the minor version", "tag minor"),
 		cli.Example("Bump and push the tag to the remote", "tag minor --push"),
 		cli.Example("Do not prompt for confirmation", "tag minor --push --force"),
-		cli.Flag(&push, "push", 'p', "Push the tag to the remote"),
-		cli.Flag(&force, "force", 'f', "Bypass confirmation prompt"),
-		cli.Flag(&dryRun, "dry-run", 'd', "Print what would have happened"),
+		cli.Example("Issue a release candidate", "tag minor --pre rc"),
+		cli.Flag(&options.Push, "push", 'p', "Push the tag to the remote"),
+		cli.Flag(&options.Remote, "remote", flag.NoShortHand, "Push to this remote instead of the configured one"),
+		cli.Flag(&options.Force, "force", 'f', "Bypass confirmation prompt"),
+		cli.Flag(&options.DryRun, "dry-run", 'd', "Print what would have happened"),
+		cli.Flag(&options.AllowEmptyChangelog, "allow-empty-changelog", flag.NoShortHand, "Allow an empty Unreleased changelog section"),
+		cli.Flag(&options.Pre, "pre", flag.NoShortHand, "Issue a pre-release with this label e.g. rc"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
 		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
 			cwd, err := os.Getwd()
 			if err != nil {
 				return err
 			}
-			tag, err := app.New(cwd, os.Stdout, os.Stderr)
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
 			if err != nil {
 				return err
 			}
-			return tag.Minor(push, force, dryRun)
+			tag = tag.WithJSON(g.json)
+			return tag.Minor(ctx, options)
 		}),
 	)
 	if err != nil {
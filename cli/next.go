@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/cli/flag"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	nextLong = `
+The bump type is one of major, minor, patch, pre or release and the next
+version is worked out exactly as the matching bump command would, but nothing
+is changed: there is no prompt, no files are touched and no commits or tags
+are made.
+
+By default just the version is printed e.g. "1.3.0", pass a Go template with
+"--format" to control the output. The template has {{.Current}} and {{.Next}}
+(with .Major, .Minor, .Patch, .Prerelease and .Build fields) as well as {{.Tag}}
+available.
+`
+)
+
+// buildNext builds and returns the next subcommand.
+func (g *globalFlags) buildNext() (*cli.Command, error) {
+	var (
+		options app.NextOptions
+		bump    string
+		module  string
+	)
+	cmd, err := cli.New(
+		"next",
+		cli.Short("Show the next version without bumping"),
+		cli.Long(nextLong),
+		cli.Example("Show the next minor version", "tag next minor"),
+		cli.Example("Show the next release candidate", "tag next minor --pre rc"),
+		cli.Example("Show just the major and minor", "tag next patch --format '{{.Next.Major}}.{{.Next.Minor}}'"),
+		cli.Arg(&bump, "bump", "The type of bump: major, minor, patch, pre or release"),
+		cli.Flag(&options.Pre, "pre", flag.NoShortHand, "Pre-release label e.g. rc, or the label to switch to for pre"),
+		cli.Flag(&options.Format, "format", flag.NoShortHand, "Go template to format the output with"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			return tag.Next(ctx, bump, options)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
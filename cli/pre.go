@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/cli/flag"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	preLong = `
+The current version must already be a pre-release (e.g. "v1.3.0-rc.1"),
+to start a new pre-release use the "--pre" flag on the major, minor
+or patch commands.
+
+By default the label of the current pre-release is kept and the counter
+is incremented, e.g. "v1.3.0-rc.1" becomes "v1.3.0-rc.2". The label can be
+changed with "-l/--label" in which case the counter for that label is
+worked out from the existing tags.
+
+You may also push the tag to any configured remote
+with the "-p/--push" flag, "--remote <name>" picks which.
+
+You will be prompted for confirmation before bumping, this
+can be bypassed by passing the "-f/--force" flag.
+
+If the "-d/--dry-run" flag is used, tag will simply print what would
+have happened, but not do anything. This is useful for checking you have
+set everything up correctly.
+`
+)
+
+// buildPre builds and returns the pre subcommand.
+func (g *globalFlags) buildPre() (*cli.Command, error) {
+	var (
+		options app.BumpOptions
+		module  string
+	)
+	cmd, err := cli.New(
+		"pre",
+		cli.Short("Bump the pre-release counter and issue a new tag"),
+		cli.Long(preLong),
+		cli.Example("Bump the pre-release counter", "tag pre"),
+		cli.Example("Move from a beta to a release candidate", "tag pre --label rc"),
+		cli.Example("Do not prompt for confirmation", "tag pre --push --force"),
+		cli.Flag(&options.Pre, "label", 'l', "Switch to a different pre-release label"),
+		cli.Flag(&options.Push, "push", 'p', "Push the tag to the remote"),
+		cli.Flag(&options.Remote, "remote", flag.NoShortHand, "Push to this remote instead of the configured one"),
+		cli.Flag(&options.Force, "force", 'f', "Bypass confirmation prompt"),
+		cli.Flag(&options.DryRun, "dry-run", 'd', "Print what would have happened"),
+		cli.Flag(&options.AllowEmptyChangelog, "allow-empty-changelog", flag.NoShortHand, "Allow an empty Unreleased changelog section"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			tag = tag.WithJSON(g.json)
+			return tag.Pre(ctx, options)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/cli/flag"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	releaseLong = `
+Promotes the current pre-release to a full release by dropping
+the pre-release part, e.g. "v1.3.0-rc.4" becomes "v1.3.0".
+
+You may also push the tag to any configured remote
+with the "-p/--push" flag, "--remote <name>" picks which.
+
+You will be prompted for confirmation before bumping, this
+can be bypassed by passing the "-f/--force" flag.
+
+If the "-d/--dry-run" flag is used, tag will simply print what would
+have happened, but not do anything. This is useful for checking you have
+set everything up correctly.
+`
+)
+
+// buildRelease builds and returns the release subcommand.
+func (g *globalFlags) buildRelease() (*cli.Command, error) {
+	var (
+		options app.BumpOptions
+		module  string
+	)
+	cmd, err := cli.New(
+		"release",
+		cli.Short("Promote a pre-release and issue a new tag"),
+		cli.Long(releaseLong),
+		cli.Example("Promote the current pre-release", "tag release"),
+		cli.Example("Do not prompt for confirmation", "tag release --push --force"),
+		cli.Flag(&options.Push, "push", 'p', "Push the tag to the remote"),
+		cli.Flag(&options.Remote, "remote", flag.NoShortHand, "Push to this remote instead of the configured one"),
+		cli.Flag(&options.Force, "force", 'f', "Bypass confirmation prompt"),
+		cli.Flag(&options.DryRun, "dry-run", 'd', "Print what would have happened"),
+		cli.Flag(&options.AllowEmptyChangelog, "allow-empty-changelog", flag.NoShortHand, "Allow an empty Unreleased changelog section"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			tag = tag.WithJSON(g.json)
+			return tag.Release(ctx, options)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	undoLong = `
+Rolls back the most recent bump, deleting the latest tag and, if tag
+made a bump commit, resetting it so every replaced file (and the version
+in .tag.toml) goes back to how it was.
+
+Tag will refuse to undo unless the latest tag is on HEAD and HEAD is
+the bump commit, so it won't throw away any work done since.
+
+If the tag has already been pushed, you must pass the "--remote" flag
+to have tag delete it from the remote as well.
+
+You will be prompted for confirmation before undoing, this
+can be bypassed by passing the "-f/--force" flag.
+`
+)
+
+// buildUndo builds and returns the undo subcommand.
+func (g *globalFlags) buildUndo() (*cli.Command, error) {
+	var (
+		options app.UndoOptions
+		module  string
+	)
+	cmd, err := cli.New(
+		"undo",
+		cli.Short("Roll back the most recent bump"),
+		cli.Long(undoLong),
+		cli.Example("Undo the last bump", "tag undo"),
+		cli.Example("Undo a bump that was already pushed", "tag undo --remote"),
+		cli.Flag(&options.Remote, "remote", 'r', "Also delete the tag from the remote"),
+		cli.Flag(&options.Force, "force", 'f', "Bypass confirmation prompt"),
+		cli.Flag(&options.DryRun, "dry-run", 'd', "Print what would have happened"),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			return tag.Undo(ctx, options)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"go.followtheprocess.codes/cli"
+	"go.followtheprocess.codes/tag/app"
+)
+
+const (
+	verifyLong = `
+The signature is checked with git verify-tag so gpg (or ssh-keygen for ssh
+signatures, which needs gpg.ssh.allowedSignersFile set in git) must know
+about the signer's key.
+
+If no tag is given, the latest tag is verified. Exits non-zero if the tag
+is not signed or the signature is bad.
+`
+)
+
+// buildVerify builds and returns the verify subcommand.
+func (g *globalFlags) buildVerify() (*cli.Command, error) {
+	var (
+		name   string
+		module string
+	)
+	cmd, err := cli.New(
+		"verify",
+		cli.Short("Check the signature on a tag"),
+		cli.Long(verifyLong),
+		cli.Example("Verify the latest tag", "tag verify"),
+		cli.Example("Verify a specific tag", "tag verify v1.2.3"),
+		cli.Arg(&name, "tag", "The tag to verify, defaults to the latest", cli.ArgDefault("")),
+		cli.Flag(&module, "module", 'm', "Operate on the [[module]] with this name or path"),
+		cli.Run(func(ctx context.Context, cmd *cli.Command) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			tag, err := app.New(cwd, g.config, os.Stdout, os.Stderr)
+			if err != nil {
+				return err
+			}
+			tag, err = tag.WithModule(module)
+			if err != nil {
+				return err
+			}
+			return tag.Verify(ctx, name)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"go.followtheprocess.codes/msg"
 	"go.followtheprocess.codes/tag/cli"
@@ -17,7 +19,11 @@ func main() {
 }
 
 func run() error {
-	ctx := context.Background()
+	// Ctrl-C cancels ctx so a bump can stop cleanly and put things back, a
+	// second one kills tag straight away in case that gets stuck
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
 
 	cmd, err := cli.Build()
 	if err != nil {
@@ -10,11 +10,21 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"go.followtheprocess.codes/semver"
+	"go.followtheprocess.codes/tag/hooks"
+	"go.followtheprocess.codes/tag/keypath"
 )
 
 // initContents is the contents of the initial config file created by `tag init`
@@ -30,35 +40,302 @@ var ErrNoConfigFile = errors.New("config file not found")
 
 const filePermissions = 0o644
 
+// captureName is what a hook's capture must look like to be usable as {{.Hooks.<name>}}.
+var captureName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // Config represents tags configuration settings.
 type Config struct { //nolint: recvcheck // In this case it makes sense
-	Version string `toml:"version"`
-	Git     Git    `toml:"git,omitempty"`
-	Hooks   Hooks  `toml:"hooks,omitempty"`
-	Files   []File `toml:"file,omitempty"`
+	Version   string    `toml:"version"`
+	Git       Git       `toml:"git,omitempty"`
+	Changelog Changelog `toml:"changelog,omitempty"`
+	Go        Go        `toml:"go,omitempty"`
+	Hooks     Hooks     `toml:"hooks,omitempty"`
+	Files     []File    `toml:"file,omitempty"`
+	Modules   []Module  `toml:"module,omitempty"`
 }
 
 // Git represents the git config in tag's config file.
 type Git struct {
-	DefaultBranch   string `toml:"default-branch,omitempty"`
-	MessageTemplate string `toml:"message-template,omitempty"`
-	TagTemplate     string `toml:"tag-template,omitempty"`
+	DefaultBranch   string   `toml:"default-branch,omitempty"`
+	MessageTemplate string   `toml:"message-template,omitempty"`
+	TagTemplate     string   `toml:"tag-template,omitempty"`
+	SigningKey      string   `toml:"signing-key,omitempty"`    // Key ID (or path to the key for ssh), defaults to git's user.signingkey
+	SigningFormat   string   `toml:"signing-format,omitempty"` // "gpg", "ssh" or "x509", defaults to git's gpg.format
+	Remote          string   `toml:"remote,omitempty"`         // Remote to push to, defaults to the one git push would use
+	PushMode        string   `toml:"push-mode,omitempty"`      // "branch-and-tag" (the default) or "tag"
+	PushRemotes     []string `toml:"push-remotes,omitempty"`   // Push to every one of these instead of Remote
+	SignTags        bool     `toml:"sign-tags,omitempty"`
+	SignCommits     bool     `toml:"sign-commits,omitempty"`
+}
+
+// The supported values of git.push-mode.
+const (
+	PushModeBranchAndTag = "branch-and-tag"
+	PushModeTag          = "tag"
+)
+
+// The supported values of git.signing-format.
+const (
+	SigningFormatGPG  = "gpg"
+	SigningFormatSSH  = "ssh"
+	SigningFormatX509 = "x509"
+)
+
+// Changelog represents the changelog config in tag's config file.
+//
+// If Path is empty, tag does not generate a changelog.
+type Changelog struct {
+	Path     string `toml:"path,omitempty"`
+	Style    string `toml:"style,omitempty"`    // "generate" (the default) or "keep-a-changelog"
+	Template string `toml:"template,omitempty"` // Defaults to changelog.DefaultTemplate, only used when generating
+}
+
+// Go represents the Go specific config in tag's config file.
+type Go struct {
+	RewriteModulePath bool   `toml:"rewrite-module-path,omitempty"` // Add the /vN suffix to the module and its imports on v2+ major bumps
+	Dir               string `toml:"dir,omitempty"`                 // Directory containing go.mod, defaults to the config file's directory
 }
 
 // Hooks encodes the optional hooks specified in tag's config file.
 type Hooks struct {
-	PreReplace string `toml:"pre-replace,omitempty"`
-	PreCommit  string `toml:"pre-commit,omitempty"`
-	PreTag     string `toml:"pre-tag,omitempty"`
-	PrePush    string `toml:"pre-push,omitempty"`
+	PreReplace Hook `toml:"pre-replace,omitempty"`
+	PreCommit  Hook `toml:"pre-commit,omitempty"`
+	PreTag     Hook `toml:"pre-tag,omitempty"`
+	PostTag    Hook `toml:"post-tag,omitempty"`
+	PrePush    Hook `toml:"pre-push,omitempty"`
+	PostPush   Hook `toml:"post-push,omitempty"`
+	OnError    Hook `toml:"on-error,omitempty"` // Runs if the bump fails, after everything has been put back
+}
+
+// stageHook is a [Hook] alongside the stage it runs at.
+type stageHook struct {
+	hook  Hook
+	stage hooks.HookStage
+}
+
+// stages returns every hook with its stage, in the order they run.
+func (h Hooks) stages() []stageHook {
+	return []stageHook{
+		{stage: hooks.StagePreReplace, hook: h.PreReplace},
+		{stage: hooks.StagePreCommit, hook: h.PreCommit},
+		{stage: hooks.StagePreTag, hook: h.PreTag},
+		{stage: hooks.StagePostTag, hook: h.PostTag},
+		{stage: hooks.StagePrePush, hook: h.PrePush},
+		{stage: hooks.StagePostPush, hook: h.PostPush},
+		{stage: hooks.StageOnError, hook: h.OnError},
+	}
+}
+
+// Captures returns the names of the output captured by the hooks up to and
+// including stage, in the order they run.
+func (h Hooks) Captures(stage hooks.HookStage) []string {
+	var names []string
+	for _, hook := range h.stages() {
+		if hook.stage > stage {
+			break
+		}
+		for _, command := range hook.hook.Commands {
+			if command.Capture != "" {
+				names = append(names, command.Capture)
+			}
+		}
+	}
+	return names
+}
+
+// Hook is the command, or commands, run at a hook stage.
+//
+// In the config file it's either a single command string, or an array of tables
+// (one per command) each with a run key and any of the other [HookCommand] settings.
+type Hook struct {
+	Commands []HookCommand // Run in order
+}
+
+var _ unstable.Unmarshaler = (*Hook)(nil)
+
+// NewHook returns a [Hook] that runs each of commands in turn.
+func NewHook(commands ...string) Hook {
+	var h Hook
+	for _, command := range commands {
+		h.Commands = append(h.Commands, HookCommand{Run: command})
+	}
+	return h
+}
+
+// HookCommand is one of the commands run by a [Hook].
+type HookCommand struct {
+	Env             map[string]string `toml:"env,omitempty"`               // Extra environment variables, the values may use templates
+	Run             string            `toml:"run"`                         // The shell command
+	Dir             string            `toml:"dir,omitempty"`               // Working directory, defaults to the config file's directory
+	Timeout         string            `toml:"timeout,omitempty"`           // e.g. "5m", defaults to no limit
+	Capture         string            `toml:"capture,omitempty"`           // Name to keep the command's output under for {{.Hooks.<name>}}
+	ContinueOnError bool              `toml:"continue-on-error,omitempty"` // Report a failure but carry on with the rest
+}
+
+// UnmarshalTOML implements [unstable.Unmarshaler], accepting either form of a [Hook].
+func (h *Hook) UnmarshalTOML(data []byte) error {
+	// A command string or inline array is a value, whereas each table of an
+	// array of tables is passed in on its own as the table's contents
+	var value struct {
+		V any `toml:"v"`
+	}
+	if err := toml.Unmarshal(append([]byte("v = "), data...), &value); err != nil {
+		var command HookCommand
+		if err := decodeStrict(data, &command); err != nil {
+			return hookError(err)
+		}
+		h.Commands = append(h.Commands, command)
+		return nil
+	}
+
+	switch command := value.V.(type) {
+	case string:
+		h.Commands = append(h.Commands, HookCommand{Run: command})
+	case []any:
+		var commands struct {
+			V []HookCommand `toml:"v"`
+		}
+		if err := decodeStrict(append([]byte("v = "), data...), &commands); err != nil {
+			return hookError(err)
+		}
+		h.Commands = append(h.Commands, commands.V...)
+	default:
+		return fmt.Errorf("hook must be a command or an array of tables, got %s", bytes.TrimSpace(data))
+	}
+
+	return nil
+}
+
+// hookError is a helper that describes an error decoding part of a [Hook]. Any
+// positions would be from the start of the hook, not the file, so are left out.
+func hookError(err error) error {
+	var strict *toml.StrictMissingError
+	if errors.As(err, &strict) {
+		keys := make([]string, 0, len(strict.Errors))
+		for _, problem := range strict.Errors {
+			key := problem.Key()
+			keys = append(keys, strconv.Quote(key[len(key)-1]))
+		}
+		return fmt.Errorf("unknown key %s in hook, expected run, timeout, dir, env, capture or continue-on-error", strings.Join(keys, ", "))
+	}
+	return fmt.Errorf("bad hook: %w", err)
+}
+
+// Parse returns the hook's commands ready to be run.
+func (h Hook) Parse() ([]hooks.Command, error) {
+	commands := make([]hooks.Command, 0, len(h.Commands))
+	for _, command := range h.Commands {
+		var timeout time.Duration
+		if command.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(command.Timeout); err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("timeout %q of hook command %q must be a positive duration e.g. \"5m\"", command.Timeout, command.Run)
+			}
+		}
+		commands = append(commands, hooks.Command{
+			Env:             command.Env,
+			Run:             command.Run,
+			Dir:             command.Dir,
+			Timeout:         timeout,
+			Capture:         command.Capture,
+			ContinueOnError: command.ContinueOnError,
+		})
+	}
+	return commands, nil
 }
 
 // File represents a single file tag should perform search and replace on.
 type File struct {
-	Path   string `toml:"path,omitempty"`
-	Search string `toml:"search,omitempty"`
+	Path    string `toml:"path,omitempty"`
+	Search  string `toml:"search,omitempty"`
+	Replace string `toml:"replace,omitempty"` // Inferred from Search if not given, required if Regex is true
+	Format  string `toml:"format,omitempty"`  // Edit Key in a "toml", "json" or "yaml" document rather than searching text
+	Key     string `toml:"key,omitempty"`     // Dotted key path to the version when Format is set e.g. "tool.poetry.version"
+	Regex   bool   `toml:"regex,omitempty"`   // Search is a regular expression, Replace may refer to its capture groups
+}
 
-	Replace string `toml:"-"` // Not part of the config, inferred from `Search`
+// Module is a separately versioned part of a monorepo, it has its own version,
+// files and hooks and its tags are prefixed e.g. "api/v1.2.3".
+//
+// Paths in Files, Changelog and Go are relative to the module's Path.
+type Module struct {
+	Name      string    `toml:"name,omitempty"`   // Used to select the module, defaults to Path
+	Path      string    `toml:"path"`             // Directory of the module relative to the repo root
+	Prefix    string    `toml:"prefix,omitempty"` // Tag prefix, defaults to Path followed by "/"
+	Version   string    `toml:"version"`
+	Changelog Changelog `toml:"changelog,omitempty"`
+	Go        Go        `toml:"go,omitempty"`
+	Hooks     Hooks     `toml:"hooks,omitempty"`
+	Files     []File    `toml:"file,omitempty"`
+}
+
+// ID returns the name the module is selected by.
+func (m Module) ID() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return m.Path
+}
+
+// TagPrefix returns the prefix of the module's tags.
+func (m Module) TagPrefix() string {
+	if m.Prefix != "" {
+		return m.Prefix
+	}
+	return strings.TrimSuffix(m.Path, "/") + "/"
+}
+
+// Module returns the [[module]] selected by name, along with its index in
+// the config file.
+func (c Config) Module(name string) (Module, int, error) {
+	ids := make([]string, 0, len(c.Modules))
+	for i, module := range c.Modules {
+		if module.ID() == name {
+			if module.Path == "" {
+				return Module{}, 0, fmt.Errorf("module %q has no path", name)
+			}
+			if module.Version == "" {
+				return Module{}, 0, fmt.Errorf("module %q has no version", name)
+			}
+			return module, i, nil
+		}
+		ids = append(ids, module.ID())
+	}
+
+	if len(ids) == 0 {
+		return Module{}, 0, fmt.Errorf("no module named %q, there are no [[module]] tables in the config file", name)
+	}
+	return Module{}, 0, fmt.Errorf("no module named %q, expected one of %s", name, strings.Join(ids, ", "))
+}
+
+// Find looks for the config file in dir and then each directory above it, up to
+// the root of the git repository (the first with a .git), returning its path or
+// [ErrNoConfigFile] if there isn't one.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(dir, Filename)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("could not check for %s: %w", path, err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			// The top of the repo, a config file further up isn't for this project
+			return "", ErrNoConfigFile
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrNoConfigFile
+		}
+		dir = parent
+	}
 }
 
 // Load reads Config from a file.
@@ -83,20 +360,233 @@ func Load(path string) (Config, error) {
 			TagTemplate:     "v{{.Next}}",
 		},
 	}
-	if err := toml.Unmarshal(raw, &cfg); err != nil {
-		return Config{}, fmt.Errorf("toml deserialize error: %w", err)
+	if err := decodeStrict(raw, &cfg); err != nil {
+		return Config{}, decodeError(path, err)
 	}
 
 	return cfg, nil
 }
 
-// Save saves the config to disk.
-func (c Config) Save(path string) error {
-	raw, err := toml.Marshal(c)
+// decodeStrict is a helper that decodes raw toml into v, where any keys v doesn't
+// have are an error.
+func decodeStrict(raw []byte, v any) error {
+	return toml.NewDecoder(bytes.NewReader(raw)).DisallowUnknownFields().EnableUnmarshalerInterface().Decode(v)
+}
+
+// decodeError is a helper that turns a toml decoding error into one that points to
+// the exact line and column (and key, if it's unknown) at fault.
+func decodeError(path string, err error) error {
+	var strict *toml.StrictMissingError
+	if errors.As(err, &strict) {
+		problems := make([]error, 0, len(strict.Errors))
+		for _, problem := range strict.Errors {
+			line, column := problem.Position()
+			problems = append(problems, fmt.Errorf("%s:%d:%d: unknown key %q", path, line, column, strings.Join(problem.Key(), ".")))
+		}
+		return errors.Join(problems...)
+	}
+
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		line, column := decodeErr.Position()
+		return fmt.Errorf("%s:%d:%d: %w", path, line, column, err)
+	}
+
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// Validate checks the config is usable without changing anything, that every version
+// is valid semver and every template parses. Whether every [[file]] exists is left to
+// [Config.ValidateFiles], as only the commands that use them need them to.
+//
+// All the problems found are returned, not just the first.
+func (c Config) Validate() error {
+	var problems []error
+
+	if _, err := semver.Parse(c.Version); err != nil {
+		problems = append(problems, fmt.Errorf("version %q is not valid semver: %w", c.Version, err))
+	}
+
+	switch c.Git.SigningFormat {
+	case "", SigningFormatGPG, SigningFormatSSH, SigningFormatX509:
+	default:
+		problems = append(problems, fmt.Errorf(
+			"git.signing-format %q must be one of %q, %q or %q",
+			c.Git.SigningFormat,
+			SigningFormatGPG,
+			SigningFormatSSH,
+			SigningFormatX509,
+		))
+	}
+
+	switch c.Git.PushMode {
+	case "", PushModeBranchAndTag, PushModeTag:
+	default:
+		problems = append(problems, fmt.Errorf(
+			"git.push-mode %q must be one of %q or %q",
+			c.Git.PushMode,
+			PushModeBranchAndTag,
+			PushModeTag,
+		))
+	}
+
+	if c.Git.Remote != "" && len(c.Git.PushRemotes) != 0 {
+		problems = append(problems, errors.New("git.remote and git.push-remotes cannot both be set"))
+	}
+	if slices.Contains(c.Git.PushRemotes, "") {
+		problems = append(problems, errors.New("git.push-remotes cannot contain an empty remote"))
+	}
+
+	problems = append(problems, validateTemplates(c, "")...)
+	problems = append(problems, validateFiles(c.Files, "")...)
+
+	seen := make(map[string]bool, len(c.Modules))
+	for i, module := range c.Modules {
+		where := fmt.Sprintf("module %s: ", module.ID())
+		if module.Path == "" {
+			problems = append(problems, fmt.Errorf("module %d: path is required", i))
+		}
+		if seen[module.ID()] {
+			problems = append(problems, fmt.Errorf("%smore than one module with this name", where))
+		}
+		seen[module.ID()] = true
+
+		if _, err := semver.Parse(module.Version); err != nil {
+			problems = append(problems, fmt.Errorf("%sversion %q is not valid semver: %w", where, module.Version, err))
+		}
+
+		modCfg := Config{Version: module.Version, Git: c.Git, Changelog: module.Changelog, Hooks: module.Hooks, Files: module.Files}
+		problems = append(problems, validateTemplates(modCfg, where)...)
+		problems = append(problems, validateFiles(module.Files, where)...)
+	}
+
+	return errors.Join(problems...)
+}
+
+// ValidateFiles checks every [[file]], including the modules', exists relative to dir.
+//
+// Globs aren't checked, they're matched against the files git knows about when bumping.
+func (c Config) ValidateFiles(dir string) error {
+	problems := missingFiles(dir, c.Files, "")
+	for _, module := range c.Modules {
+		where := fmt.Sprintf("module %s: ", module.ID())
+		problems = append(problems, missingFiles(filepath.Join(dir, module.Path), module.Files, where)...)
+	}
+	return errors.Join(problems...)
+}
+
+// validateTemplates is a helper that checks all the templates in c parse and render.
+func validateTemplates(c Config, where string) []error {
+	var problems []error
+
+	if c.Changelog.Template != "" {
+		if _, err := template.New("changelog").Parse(c.Changelog.Template); err != nil {
+			problems = append(problems, fmt.Errorf("%scould not parse changelog.template: %w", where, err))
+		}
+	}
+
+	// Render does all the parsing and checking of the rest, on a copy so c is untouched
+	rendered := c
+	if err := rendered.Render(c.Version, c.Version, noOutput(c.Hooks.Captures(hooks.StagePreTag))); err != nil {
+		problems = append(problems, fmt.Errorf("%s%w", where, err))
+	} else {
+		// The commit is made before the pre-tag hook runs, so its message can only
+		// use what's captured before then
+		commit := c
+		commit.Files, commit.Git.TagTemplate = nil, ""
+		if err := commit.Render(c.Version, c.Version, noOutput(c.Hooks.Captures(hooks.StagePreCommit))); err != nil {
+			problems = append(problems, fmt.Errorf("%s%w (the pre-tag hook runs after the commit)", where, err))
+		}
+	}
+
+	// Hooks are rendered as they run, with the tag too
+	vars := hooks.Vars{Current: c.Version, Next: c.Version, Tag: "v" + c.Version}
+	seen := make(map[string]bool)
+	for _, hook := range c.Hooks.stages() {
+		if _, err := hook.hook.Parse(); err != nil {
+			problems = append(problems, fmt.Errorf("%shooks.%s: %w", where, hook.stage.Key(), err))
+		}
+		for _, command := range hook.hook.Commands {
+			if command.Run == "" {
+				problems = append(problems, fmt.Errorf("%shooks.%s: every command needs a run key", where, hook.stage.Key()))
+			}
+			if command.Capture != "" {
+				switch {
+				case !captureName.MatchString(command.Capture):
+					problems = append(problems, fmt.Errorf("%shooks.%s: capture %q must be a name made of letters, digits and underscores", where, hook.stage.Key(), command.Capture))
+				case seen[command.Capture]:
+					problems = append(problems, fmt.Errorf("%shooks.%s: capture %q is used more than once", where, hook.stage.Key(), command.Capture))
+				case hook.stage > hooks.StagePreTag:
+					problems = append(problems, fmt.Errorf("%shooks.%s: capture %q would never be used, only hooks up to pre-tag can capture output", where, hook.stage.Key(), command.Capture))
+				}
+				seen[command.Capture] = true
+			}
+			if _, err := hooks.Render(hook.stage, command.Run, vars); err != nil {
+				problems = append(problems, fmt.Errorf("%s%w", where, err))
+			}
+			for _, value := range command.Env {
+				if _, err := hooks.Render(hook.stage, value, vars); err != nil {
+					problems = append(problems, fmt.Errorf("%s%w", where, err))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// noOutput is a helper that returns captured output for names where nothing was captured.
+func noOutput(names []string) map[string]string {
+	captured := make(map[string]string, len(names))
+	for _, name := range names {
+		captured[name] = ""
+	}
+	return captured
+}
+
+// validateFiles is a helper that checks every file has a path.
+func validateFiles(files []File, where string) []error {
+	var problems []error
+	for _, file := range files {
+		if file.Path == "" {
+			problems = append(problems, fmt.Errorf("%sfile.path is required", where))
+		}
+	}
+	return problems
+}
+
+// missingFiles is a helper that returns a problem for every file that doesn't exist
+// relative to dir, those with no path are reported by validateFiles instead.
+func missingFiles(dir string, files []File, where string) []error {
+	var problems []error
+	for _, file := range files {
+		if file.Path == "" || strings.ContainsAny(file.Path, "*?[") {
+			// Globs are matched against the files git knows about when bumping
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, file.Path)); err != nil {
+			problems = append(problems, fmt.Errorf("%sfile %s does not exist", where, file.Path))
+		}
+	}
+	return problems
+}
+
+// SetVersion updates the version key of the config file at path in place,
+// every other byte of the file (comments, key order, quoting) is left
+// exactly as it was.
+//
+// The key is "version" for the top level version, or e.g. "module.1.version"
+// for the version of the second [[module]].
+func SetVersion(path, key, version string) error {
+	raw, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("toml serialise error: %w", err)
+		return fmt.Errorf("could not read %s: %w", path, err)
 	}
-	if err := os.WriteFile(path, raw, filePermissions); err != nil {
+	updated, err := keypath.Set(keypath.TOML, raw, key, version)
+	if err != nil {
+		return fmt.Errorf("could not update version in %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, updated, filePermissions); err != nil {
 		return fmt.Errorf("could not write %s: %w", path, err)
 	}
 	return nil
@@ -104,13 +594,18 @@ func (c Config) Save(path string) error {
 
 // Render replaces the special values {{.Current}} and {{.Next}} in the
 // search and replace templates as well as the commit and tag messages.
-func (c *Config) Render(current, next string) error {
+//
+// The commit and tag messages may also use {{.Hooks.<name>}} for the output
+// captured by hooks, captured has it by name. Naming a capture it doesn't have
+// is an error.
+func (c *Config) Render(current, next string, captured map[string]string) error {
 	searchTemplate := template.New("search")
 	replaceTemplate := template.New("replace")
-	tagTemplate := template.New("tag")
-	commitTemplate := template.New("commit")
+	tagTemplate := template.New("tag").Option("missingkey=error")
+	commitTemplate := template.New("commit").Option("missingkey=error")
 
 	vars := map[string]string{"Current": current, "Next": next}
+	messageVars := map[string]any{"Current": current, "Next": next, "Hooks": captured}
 
 	// Render the tag and commit templates
 	tagParsed, err := tagTemplate.Parse(c.Git.TagTemplate)
@@ -125,11 +620,11 @@ func (c *Config) Render(current, next string) error {
 	tagOut := &bytes.Buffer{}
 	commitOut := &bytes.Buffer{}
 
-	if err := tagParsed.Execute(tagOut, vars); err != nil {
+	if err := tagParsed.Execute(tagOut, messageVars); err != nil {
 		return fmt.Errorf("could not execute tag-template: %w", err)
 	}
 
-	if err := commitParsed.Execute(commitOut, vars); err != nil {
+	if err := commitParsed.Execute(commitOut, messageVars); err != nil {
 		return fmt.Errorf("could not execute message-template: %w", err)
 	}
 
@@ -141,19 +636,56 @@ func (c *Config) Render(current, next string) error {
 
 	// Now for the files
 	for _, file := range c.Files {
+		if file.Format != "" {
+			if !keypath.IsSupported(file.Format) {
+				return fmt.Errorf("unsupported file.format %q for file %s, expected one of %s", file.Format, file.Path, strings.Join(keypath.Formats(), ", "))
+			}
+			if file.Key == "" {
+				return fmt.Errorf("file.key is required when file.format is set for file %s", file.Path)
+			}
+			if file.Regex {
+				return fmt.Errorf("file.regex cannot be used with file.format for file %s", file.Path)
+			}
+			if file.Search == "" {
+				// The value of the key is just the version
+				file.Search = "{{.Current}}"
+			}
+		}
+
+		searchVars := vars
+		if file.Regex {
+			// The versions are literal text inside the regex, "1.2.3" must not match "1x2y3"
+			searchVars = map[string]string{"Current": regexp.QuoteMeta(current), "Next": regexp.QuoteMeta(next)}
+		}
+
+		replace := file.Replace
+		if replace == "" {
+			if file.Regex {
+				return fmt.Errorf("file.replace is required for regex search in file %s", file.Path)
+			}
+			replace = strings.ReplaceAll(file.Search, "{{.Current}}", "{{.Next}}")
+		}
+
 		searchParsed, err := searchTemplate.Parse(file.Search)
 		if err != nil {
 			return fmt.Errorf("could not parse file.search for file %s: %w", file.Path, err)
 		}
 
-		replaceParsed, err := replaceTemplate.Parse(strings.ReplaceAll(file.Search, "{{.Current}}", "{{.Next}}"))
+		if file.Regex {
+			if group := bareGroup(replace); group != "" {
+				// Once rendered "$1{{.Next}}" is "$11.2.3", which regexp reads as group 11
+				return fmt.Errorf("file.replace for file %s runs %s into a template, write it as ${%s} instead", file.Path, group, group[1:])
+			}
+		}
+
+		replaceParsed, err := replaceTemplate.Parse(replace)
 		if err != nil {
-			return fmt.Errorf("could not infer file.replace for file %s: %w", file.Path, err)
+			return fmt.Errorf("could not parse file.replace for file %s: %w", file.Path, err)
 		}
 
 		searchOut := &bytes.Buffer{}
 		replaceOut := &bytes.Buffer{}
-		if err := searchParsed.Execute(searchOut, vars); err != nil {
+		if err := searchParsed.Execute(searchOut, searchVars); err != nil {
 			return fmt.Errorf("could not execute file.search for file %s: %w", file.Path, err)
 		}
 
@@ -161,6 +693,12 @@ func (c *Config) Render(current, next string) error {
 			return fmt.Errorf("could not execute file.replace for file %s: %w", file.Path, err)
 		}
 
+		if file.Regex {
+			if _, err := regexp.Compile(searchOut.String()); err != nil {
+				return fmt.Errorf("file.search for file %s is not a valid regex: %w", file.Path, err)
+			}
+		}
+
 		file.Search = searchOut.String()
 		file.Replace = replaceOut.String()
 		rendered = append(rendered, file)
@@ -170,6 +708,36 @@ func (c *Config) Render(current, next string) error {
 	return nil
 }
 
+// bareGroup is a helper that returns the first capture group reference in a regex
+// replacement written without braces e.g. "$1" that runs straight into a template
+// action, or "" if there isn't one.
+func bareGroup(replace string) string {
+	for i := 0; i < len(replace); i++ {
+		if replace[i] != '$' {
+			continue
+		}
+		if strings.HasPrefix(replace[i+1:], "$") {
+			// "$$" is a literal dollar
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(replace) && isNameByte(replace[end]) {
+			end++
+		}
+		if end > i+1 && strings.HasPrefix(replace[end:], "{{") {
+			return replace[i:end]
+		}
+	}
+	return ""
+}
+
+// isNameByte reports whether b can be part of a capture group name, as far as
+// [regexp.Regexp.Expand] is concerned.
+func isNameByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
 // Init returns a toml encoded string of the initial tag config.
 func Init() string {
 	return initContents
@@ -1,10 +1,12 @@
 package config_test
 
 import (
-	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"go.followtheprocess.codes/tag/config"
@@ -28,10 +30,10 @@ func TestLoad(t *testing.T) {
 					TagTemplate:     "taggy v{{.Next}}",
 				},
 				Hooks: config.Hooks{
-					PreReplace: "echo 'I run before doing anything'",
-					PreCommit:  "echo 'I run after replacing but before committing changes'",
-					PreTag:     "echo 'I run after committing changes but before tagging'",
-					PrePush:    "echo 'I run after tagging, but before pushing'",
+					PreReplace: config.NewHook("echo 'I run before doing anything'"),
+					PreCommit:  config.NewHook("echo 'I run after replacing but before committing changes'"),
+					PreTag:     config.NewHook("echo 'I run after committing changes but before tagging'"),
+					PrePush:    config.NewHook("echo 'I run after tagging, but before pushing'"),
 				},
 				Files: []config.File{
 					{
@@ -57,10 +59,10 @@ func TestLoad(t *testing.T) {
 					TagTemplate:     "v{{.Next}}",
 				},
 				Hooks: config.Hooks{
-					PreReplace: "echo 'I run before doing anything'",
-					PreCommit:  "echo 'I run after replacing but before committing changes'",
-					PreTag:     "echo 'I run after committing changes but before tagging'",
-					PrePush:    "echo 'I run after tagging, but before pushing'",
+					PreReplace: config.NewHook("echo 'I run before doing anything'"),
+					PreCommit:  config.NewHook("echo 'I run after replacing but before committing changes'"),
+					PreTag:     config.NewHook("echo 'I run after committing changes but before tagging'"),
+					PrePush:    config.NewHook("echo 'I run after tagging, but before pushing'"),
 				},
 				Files: []config.File{
 					{
@@ -126,6 +128,81 @@ func TestLoad(t *testing.T) {
 			want:    config.Config{},
 			wantErr: true,
 		},
+		{
+			name: "changelog",
+			file: "changelog.toml",
+			want: config.Config{
+				Version: "0.1.0",
+				Git: config.Git{
+					DefaultBranch:   "main",
+					MessageTemplate: "Bump version {{.Current}} -> {{.Next}}",
+					TagTemplate:     "v{{.Next}}",
+				},
+				Changelog: config.Changelog{
+					Path:     "docs/CHANGELOG.md",
+					Template: "## {{.Tag}}\n",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "regex file",
+			file: "regex.toml",
+			want: config.Config{
+				Version: "0.1.0",
+				Git: config.Git{
+					DefaultBranch:   "main",
+					MessageTemplate: "Bump version {{.Current}} -> {{.Next}}",
+					TagTemplate:     "v{{.Next}}",
+				},
+				Files: []config.File{
+					{
+						Path:   "README.md",
+						Search: "My project, version {{.Current}}",
+					},
+					{
+						Path:    "Cargo.toml",
+						Search:  `version\s*=\s*(?P<quote>["\x27]){{.Current}}["\x27]`,
+						Replace: "version = ${quote}{{.Next}}${quote}",
+						Regex:   true,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "hook forms",
+			file: "hooks.toml",
+			want: config.Config{
+				Version: "0.1.0",
+				Git: config.Git{
+					DefaultBranch:   "main",
+					MessageTemplate: "Bump version {{.Current}} -> {{.Next}}",
+					TagTemplate:     "v{{.Next}}",
+				},
+				Hooks: config.Hooks{
+					PreCommit: config.NewHook("cargo build"),
+					PreTag: config.Hook{
+						Commands: []config.HookCommand{
+							{
+								Run:     "go build ./...",
+								Timeout: "5m",
+								Dir:     "cmd/tag",
+								Env:     map[string]string{"CGO_ENABLED": "0"},
+							},
+							{Run: "./scripts/notes.sh", Capture: "Notes"},
+						},
+					},
+					PostPush: config.Hook{
+						Commands: []config.HookCommand{
+							{Run: "echo one"},
+							{Run: "echo two", ContinueOnError: true},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:    "not toml",
 			file:    "nottoml.json",
@@ -138,6 +215,12 @@ func TestLoad(t *testing.T) {
 			want:    config.Config{},
 			wantErr: true,
 		},
+		{
+			name:    "unknown key",
+			file:    "unknown.toml",
+			want:    config.Config{},
+			wantErr: true,
+		},
 	}
 
 	cwd, err := os.Getwd()
@@ -161,6 +244,52 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoadUnknownKey(t *testing.T) {
+	file := filepath.Join("testdata", "unknown.toml")
+	_, err := config.Load(file)
+	if err == nil {
+		t.Fatal("Load did not return an error for an unknown key")
+	}
+
+	want := file + `:8:1: unknown key "file.serach"`
+	if err.Error() != want {
+		t.Errorf("Wrong error: got %q, wanted %q", err.Error(), want)
+	}
+}
+
+func TestLoadBadHook(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+	}{
+		{
+			name: "not a command",
+			toml: "version = '0.1.0'\n[hooks]\npre-tag = 1\n",
+		},
+		{
+			name: "unknown key in table",
+			toml: "version = '0.1.0'\n[[hooks.pre-tag]]\ncommand = 'make'\n",
+		},
+		{
+			name: "unknown key inline",
+			toml: "version = '0.1.0'\n[hooks]\npre-tag = [{command = 'make'}]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), config.Filename)
+			if err := os.WriteFile(file, []byte(tt.toml), 0o644); err != nil {
+				t.Fatalf("could not write config file: %v", err)
+			}
+
+			if _, err := config.Load(file); err == nil {
+				t.Error("Load did not return an error")
+			}
+		})
+	}
+}
+
 func TestRender(t *testing.T) {
 	cfg := config.Config{
 		Version: "1.0.0",
@@ -180,7 +309,7 @@ func TestRender(t *testing.T) {
 			},
 		},
 	}
-	if err := cfg.Render("1.0.0", "2.0.0"); err != nil {
+	if err := cfg.Render("1.0.0", "2.0.0", nil); err != nil {
 		t.Fatalf("Render returned an error: %v", err)
 	}
 
@@ -210,123 +339,530 @@ func TestRender(t *testing.T) {
 	}
 }
 
-func TestSave(t *testing.T) {
+func TestRenderFile(t *testing.T) {
 	tests := []struct {
-		name string
-		file string
-		cfg  config.Config
+		name    string
+		file    config.File
+		want    config.File
+		wantErr bool
 	}{
 		{
-			name: "full",
-			file: "full.toml",
-			cfg: config.Config{
-				Version: "0.1.0",
-				Git: config.Git{
-					DefaultBranch:   "master",
-					MessageTemplate: "Custom version {{.Current}} -> {{.Next}}",
-					TagTemplate:     "taggy v{{.Next}}",
-				},
-				Hooks: config.Hooks{
-					PreReplace: "echo 'I run before doing anything'",
-					PreCommit:  "echo 'I run after replacing but before committing changes'",
-					PreTag:     "echo 'I run after committing changes but before tagging'",
-					PrePush:    "echo 'I run after tagging, but before pushing'",
-				},
-				Files: []config.File{
-					{
-						Path:   "pyproject.toml",
-						Search: `version = "{{.Current}}"`,
-					},
-					{
-						Path:   "README.md",
-						Search: "My project, version {{.Current}}",
-					},
-				},
+			name: "quoted versions",
+			file: config.File{
+				Path:    "Cargo.toml",
+				Search:  `version\s*=\s*(?P<quote>["']){{.Current}}["']`,
+				Replace: "version = ${quote}{{.Next}}${quote}",
+				Regex:   true,
+			},
+			want: config.File{
+				Path:    "Cargo.toml",
+				Search:  `version\s*=\s*(?P<quote>["'])1\.0\.0["']`,
+				Replace: "version = ${quote}2.0.0${quote}",
+				Regex:   true,
 			},
+			wantErr: false,
 		},
 		{
-			name: "missing git",
-			file: "nogit.toml",
-			cfg: config.Config{
-				Version: "0.1.0",
-				Hooks: config.Hooks{
-					PreReplace: "echo 'I run before doing anything'",
-					PreCommit:  "echo 'I run after replacing but before committing changes'",
-					PreTag:     "echo 'I run after committing changes but before tagging'",
-					PrePush:    "echo 'I run after tagging, but before pushing'",
-				},
-				Files: []config.File{
-					{
-						Path:   "pyproject.toml",
-						Search: `version = "{{.Current}}"`,
-					},
-					{
-						Path:   "README.md",
-						Search: "My project, version {{.Current}}",
-					},
-				},
+			name: "numbered group",
+			file: config.File{
+				Path:    "VERSION",
+				Search:  `(v?){{.Current}}`,
+				Replace: "${1}{{.Next}}",
+				Regex:   true,
+			},
+			want: config.File{
+				Path:    "VERSION",
+				Search:  `(v?)1\.0\.0`,
+				Replace: "${1}2.0.0",
+				Regex:   true,
 			},
+			wantErr: false,
 		},
 		{
-			name: "missing hooks",
-			file: "nohooks.toml",
-			cfg: config.Config{
-				Version: "0.1.0",
-				Files: []config.File{
-					{
-						Path:   "pyproject.toml",
-						Search: `version = "{{.Current}}"`,
-					},
-					{
-						Path:   "README.md",
-						Search: "My project, version {{.Current}}",
-					},
-				},
+			name: "bare group before a template",
+			file: config.File{
+				Path:    "VERSION",
+				Search:  `(v?){{.Current}}`,
+				Replace: "$1{{.Next}}",
+				Regex:   true,
 			},
+			wantErr: true,
 		},
 		{
-			name: "minimal",
-			file: "minimal.toml",
-			cfg: config.Config{
-				Version: "0.1.0",
+			name: "bare named group before a template",
+			file: config.File{
+				Path:    "Cargo.toml",
+				Search:  `(?P<quote>["']){{.Current}}`,
+				Replace: "$quote{{.Next}}",
+				Regex:   true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "literal dollar before a template",
+			file: config.File{
+				Path:    "prices.txt",
+				Search:  `\$ {{.Current}}`,
+				Replace: "$${{.Next}}",
+				Regex:   true,
+			},
+			want: config.File{
+				Path:    "prices.txt",
+				Search:  `\$ 1\.0\.0`,
+				Replace: "$$2.0.0",
+				Regex:   true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "explicit replace without regex",
+			file: config.File{
+				Path:    "README.md",
+				Search:  "Version {{.Current}}",
+				Replace: "Version {{.Next}} (was {{.Current}})",
+			},
+			want: config.File{
+				Path:    "README.md",
+				Search:  "Version 1.0.0",
+				Replace: "Version 2.0.0 (was 1.0.0)",
+			},
+			wantErr: false,
+		},
+		{
+			name: "format defaults",
+			file: config.File{
+				Path:   "package.json",
+				Format: "json",
+				Key:    "version",
+			},
+			want: config.File{
+				Path:    "package.json",
+				Search:  "1.0.0",
+				Replace: "2.0.0",
+				Format:  "json",
+				Key:     "version",
+			},
+			wantErr: false,
+		},
+		{
+			name: "format with prefix",
+			file: config.File{
+				Path:   "Chart.yaml",
+				Search: "v{{.Current}}",
+				Format: "yaml",
+				Key:    "appVersion",
+			},
+			want: config.File{
+				Path:    "Chart.yaml",
+				Search:  "v1.0.0",
+				Replace: "v2.0.0",
+				Format:  "yaml",
+				Key:     "appVersion",
 			},
+			wantErr: false,
+		},
+		{
+			name: "format missing key",
+			file: config.File{
+				Path:   "package.json",
+				Format: "json",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown format",
+			file: config.File{
+				Path:   "pom.xml",
+				Format: "xml",
+				Key:    "version",
+			},
+			wantErr: true,
+		},
+		{
+			name: "format and regex",
+			file: config.File{
+				Path:    "package.json",
+				Format:  "json",
+				Key:     "version",
+				Replace: "{{.Next}}",
+				Regex:   true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing replace",
+			file: config.File{
+				Path:   "Cargo.toml",
+				Search: `version = "{{.Current}}"`,
+				Regex:  true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "bad regex",
+			file: config.File{
+				Path:    "Cargo.toml",
+				Search:  `version = (?P<quote{{.Current}}`,
+				Replace: "{{.Next}}",
+				Regex:   true,
+			},
+			wantErr: true,
 		},
 	}
 
-	cwd, err := os.Getwd()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{
+				Version: "1.0.0",
+				Files:   []config.File{tt.file},
+			}
+
+			err := cfg.Render("1.0.0", "2.0.0", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(cfg.Files[0], tt.want) {
+				t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", cfg.Files[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	original := `# My project's tag config
+version = "0.1.0" # Bumped by tag
+
+[git]
+# Keep this one as it is
+message-template = "Bump version {{.Current}} -> {{.Next}}"
+
+[[file]]
+path    = "README.md"
+search  = "version {{.Current}}"
+`
+
+	want := `# My project's tag config
+version = "0.2.0" # Bumped by tag
+
+[git]
+# Keep this one as it is
+message-template = "Bump version {{.Current}} -> {{.Next}}"
+
+[[file]]
+path    = "README.md"
+search  = "version {{.Current}}"
+`
+
+	path := filepath.Join(t.TempDir(), ".tag.toml")
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+
+	if err := config.SetVersion(path, "version", "0.2.0"); err != nil {
+		t.Fatalf("SetVersion returned an error: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
 	if err != nil {
-		t.Fatalf("could not get cwd: %v", err)
+		t.Fatalf("could not read config file: %v", err)
+	}
+
+	if string(got) != want {
+		t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", string(got), want)
+	}
+}
+
+func TestModule(t *testing.T) {
+	cfg := config.Config{
+		Version: "0.1.0",
+		Modules: []config.Module{
+			{Path: "api", Version: "1.2.0"},
+			{Name: "worker", Path: "services/worker", Version: "0.4.1"},
+			{Name: "web", Path: "web", Prefix: "frontend-", Version: "2.0.0"},
+			{Name: "broken", Path: "broken"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		module     string
+		wantPrefix string
+		wantIndex  int
+		wantErr    bool
+	}{
+		{name: "by path", module: "api", wantPrefix: "api/", wantIndex: 0},
+		{name: "by name", module: "worker", wantPrefix: "services/worker/", wantIndex: 1},
+		{name: "explicit prefix", module: "web", wantPrefix: "frontend-", wantIndex: 2},
+		{name: "path when named", module: "services/worker", wantErr: true},
+		{name: "missing", module: "nope", wantErr: true},
+		{name: "no version", module: "broken", wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tmp, err := os.CreateTemp(t.TempDir(), "config.toml*")
-			if err != nil {
-				t.Fatalf("could not create temp file: %v", err)
+			module, index, err := cfg.Module(tt.module)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Module(%q) returned %v, wanted error: %v", tt.module, err, tt.wantErr)
 			}
-			tmp.Close()
-			defer os.RemoveAll(tmp.Name())
-			if err = tt.cfg.Save(tmp.Name()); err != nil {
-				t.Fatalf("Save returned an error: %v", err)
+			if err != nil {
+				return
 			}
 
-			written, err := os.ReadFile(tmp.Name())
-			if err != nil {
-				t.Fatalf("could not read written file: %v", err)
+			if prefix := module.TagPrefix(); prefix != tt.wantPrefix {
+				t.Errorf("Wrong prefix: got %q, wanted %q", prefix, tt.wantPrefix)
 			}
+			if index != tt.wantIndex {
+				t.Errorf("Wrong index: got %d, wanted %d", index, tt.wantIndex)
+			}
+		})
+	}
+}
 
-			written = bytes.ReplaceAll(written, []byte("\r\n"), []byte("\n"))
+func TestFind(t *testing.T) {
+	tmp := t.TempDir()
+	repo := filepath.Join(tmp, "repo")
+	sub := filepath.Join(repo, "docs", "guide")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("Could not create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatalf("Could not create .git: %v", err)
+	}
 
-			golden, err := os.ReadFile(filepath.Join(cwd, "testdata", tt.file))
-			if err != nil {
-				t.Fatalf("could not read golden file: %v", err)
+	// Above the repo so it should never be found from inside it
+	if err := os.WriteFile(filepath.Join(tmp, config.Filename), []byte("version = '1.0.0'\n"), 0o644); err != nil {
+		t.Fatalf("Could not write outer config: %v", err)
+	}
+
+	if _, err := config.Find(sub); !errors.Is(err, config.ErrNoConfigFile) {
+		t.Fatalf("Find with no config in the repo returned %v, wanted %v", err, config.ErrNoConfigFile)
+	}
+
+	want := filepath.Join(repo, config.Filename)
+	if err := os.WriteFile(want, []byte("version = '0.1.0'\n"), 0o644); err != nil {
+		t.Fatalf("Could not write config: %v", err)
+	}
+
+	for _, dir := range []string{repo, sub} {
+		got, err := config.Find(dir)
+		if err != nil {
+			t.Fatalf("Find(%s) returned an error: %v", dir, err)
+		}
+		if got != want {
+			t.Errorf("Find(%s): got %s, wanted %s", dir, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("version 0.1.0"), 0o644); err != nil {
+		t.Fatalf("could not write README.md: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "api"), 0o755); err != nil {
+		t.Fatalf("could not create api dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "api", "version.txt"), []byte("0.4.0"), 0o644); err != nil {
+		t.Fatalf("could not write api/version.txt: %v", err)
+	}
+
+	valid := config.Config{
+		Version: "0.1.0",
+		Git: config.Git{
+			MessageTemplate: "Bump version {{.Current}} -> {{.Next}}",
+			TagTemplate:     "v{{.Next}}",
+		},
+		Files: []config.File{
+			{Path: "README.md", Search: "version {{.Current}}"},
+			{Path: "docs/**/*.md", Search: "version {{.Current}}"}, // Globs aren't checked
+		},
+		Modules: []config.Module{
+			{Path: "api", Version: "0.4.0", Files: []config.File{{Path: "version.txt", Search: "{{.Current}}"}}},
+		},
+	}
+
+	tests := []struct {
+		modify func(cfg *config.Config)
+		name   string
+		want   []string // Substrings of the expected errors, in order
+	}{
+		{
+			name:   "valid",
+			modify: func(cfg *config.Config) {},
+		},
+		{
+			name:   "bad version",
+			modify: func(cfg *config.Config) { cfg.Version = "one" },
+			want:   []string{`version "one" is not valid semver`},
+		},
+		{
+			name:   "bad template",
+			modify: func(cfg *config.Config) { cfg.Git.TagTemplate = "v{{.Next" },
+			// The module shares the git config so it's reported there too
+			want: []string{"could not parse tag-template", "module api: could not parse tag-template"},
+		},
+		{
+			name:   "bad signing format",
+			modify: func(cfg *config.Config) { cfg.Git.SigningFormat = "pgp" },
+			want:   []string{`git.signing-format "pgp" must be one of "gpg", "ssh" or "x509"`},
+		},
+		{
+			name:   "bad hook template",
+			modify: func(cfg *config.Config) { cfg.Hooks.PreTag = config.NewHook("make man VERSION={{.Version}}") },
+			want:   []string{"could not execute command in hook stage Pre Tag"},
+		},
+		{
+			name: "bad hook command",
+			modify: func(cfg *config.Config) {
+				cfg.Hooks.PostPush = config.Hook{Commands: []config.HookCommand{{Timeout: "soon"}}}
+			},
+			want: []string{
+				`hooks.post-push: timeout "soon" of hook command "" must be a positive duration`,
+				"hooks.post-push: every command needs a run key",
+			},
+		},
+		{
+			name: "captures",
+			modify: func(cfg *config.Config) {
+				cfg.Git.MessageTemplate = "Bump to {{.Next}}\n\n{{.Hooks.Summary}}"
+				cfg.Git.TagTemplate = "{{.Hooks.Summary}}\n\n{{.Hooks.Notes}}"
+				cfg.Hooks.PreCommit = config.Hook{Commands: []config.HookCommand{{Run: "./summary.sh", Capture: "Summary"}}}
+				cfg.Hooks.PreTag = config.Hook{Commands: []config.HookCommand{{Run: "./notes.sh", Capture: "Notes"}}}
+				cfg.Modules[0].Hooks = cfg.Hooks // It shares the templates so needs the same captures
+			},
+		},
+		{
+			name: "unknown capture",
+			modify: func(cfg *config.Config) {
+				cfg.Hooks.PreTag = config.Hook{Commands: []config.HookCommand{{Run: "./notes.sh", Capture: "Notes"}}}
+				cfg.Git.TagTemplate = "{{.Hooks.Changes}}"
+			},
+			want: []string{`could not execute tag-template`, `module api: could not execute tag-template`},
+		},
+		{
+			name: "capture after commit",
+			modify: func(cfg *config.Config) {
+				cfg.Hooks.PreTag = config.Hook{Commands: []config.HookCommand{{Run: "./notes.sh", Capture: "Notes"}}}
+				cfg.Git.MessageTemplate = "{{.Hooks.Notes}}"
+			},
+			want: []string{"(the pre-tag hook runs after the commit)", "module api: could not execute message-template"},
+		},
+		{
+			name: "bad captures",
+			modify: func(cfg *config.Config) {
+				cfg.Hooks.PreCommit = config.Hook{
+					Commands: []config.HookCommand{{Run: "./notes.sh", Capture: "release-notes"}, {Run: "./a.sh", Capture: "A"}},
+				}
+				cfg.Hooks.PreTag = config.Hook{Commands: []config.HookCommand{{Run: "./b.sh", Capture: "A"}}}
+				cfg.Hooks.PostPush = config.Hook{Commands: []config.HookCommand{{Run: "./c.sh", Capture: "C"}}}
+			},
+			want: []string{
+				`hooks.pre-commit: capture "release-notes" must be a name made of letters, digits and underscores`,
+				`hooks.pre-tag: capture "A" is used more than once`,
+				`hooks.post-push: capture "C" would never be used, only hooks up to pre-tag can capture output`,
+			},
+		},
+		{
+			name:   "bad push mode",
+			modify: func(cfg *config.Config) { cfg.Git.PushMode = "everything" },
+			want:   []string{`git.push-mode "everything" must be one of "branch-and-tag" or "tag"`},
+		},
+		{
+			name: "remote and push remotes",
+			modify: func(cfg *config.Config) {
+				cfg.Git.Remote = "origin"
+				cfg.Git.PushRemotes = []string{"origin", ""}
+			},
+			want: []string{
+				"git.remote and git.push-remotes cannot both be set",
+				"git.push-remotes cannot contain an empty remote",
+			},
+		},
+		{
+			name:   "bad changelog template",
+			modify: func(cfg *config.Config) { cfg.Changelog.Template = "{{range .Groups}}" },
+			want:   []string{"could not parse changelog.template"},
+		},
+		{
+			name: "missing file",
+			modify: func(cfg *config.Config) {
+				cfg.Files = append(cfg.Files, config.File{Path: "missing.txt", Search: "{{.Current}}"})
+			},
+			want: []string{"file missing.txt does not exist"},
+		},
+		{
+			name: "module problems",
+			modify: func(cfg *config.Config) {
+				cfg.Modules = []config.Module{
+					{Path: "api", Version: "v0.4"},
+					{Path: "api", Version: "0.1.0", Files: []config.File{{Path: "missing.txt", Search: "{{.Current}}"}}},
+				}
+			},
+			want: []string{
+				`module api: version "v0.4" is not valid semver`,
+				"module api: more than one module with this name",
+				"module api: file missing.txt does not exist",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			cfg.Files = slices.Clone(valid.Files)
+			cfg.Modules = slices.Clone(valid.Modules)
+			tt.modify(&cfg)
+
+			err := errors.Join(cfg.Validate(), cfg.ValidateFiles(dir))
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate returned an unexpected error: %v", err)
+				}
+				return
 			}
 
-			golden = bytes.ReplaceAll(golden, []byte("\r\n"), []byte("\n"))
+			if err == nil {
+				t.Fatal("Validate did not return an error")
+			}
 
-			if string(written) != string(golden) {
-				t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", string(written), string(golden))
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("Wrong number of problems, got %d:\n%v\n\nWanted %d", len(lines), err, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("Problem %d: got %q, wanted it to contain %q", i, lines[i], want)
+				}
 			}
 		})
 	}
 }
+
+func TestValidateMissingFiles(t *testing.T) {
+	cfg := config.Config{
+		Version: "0.1.0",
+		Files:   []config.File{{Path: "missing.txt", Search: "{{.Current}}"}},
+		Modules: []config.Module{
+			{Path: "api", Version: "0.4.0", Files: []config.File{{Path: "version.txt", Search: "{{.Current}}"}}},
+		},
+	}
+
+	// Only the commands that use the files need them to exist
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned an unexpected error: %v", err)
+	}
+
+	err := cfg.ValidateFiles(t.TempDir())
+	if err == nil {
+		t.Fatal("ValidateFiles did not return an error")
+	}
+	want := "file missing.txt does not exist\nmodule api: file version.txt does not exist"
+	if err.Error() != want {
+		t.Errorf("Wrong error\nGot:\t%q\nWanted:\t%q", err.Error(), want)
+	}
+}
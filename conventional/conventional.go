@@ -0,0 +1,67 @@
+// Package conventional implements parsing of commit messages following the
+// [Conventional Commits] specification, tag uses this to work out what kind
+// of bump a set of commits warrants.
+//
+// [Conventional Commits]: https://www.conventionalcommits.org
+package conventional
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrNotConventional is returned when a commit message does not follow the
+// conventional commits format.
+var ErrNotConventional = errors.New("not a conventional commit")
+
+// header matches the first line of a conventional commit e.g. "feat(cli)!: Add a thing".
+var header = regexp.MustCompile(`^(?P<type>[A-Za-z]+)(?:\((?P<scope>[^()\r\n]*)\))?(?P<breaking>!)?: (?P<description>\S.*)$`)
+
+// Commit is a parsed conventional commit.
+type Commit struct {
+	Type        string // The type of the commit e.g. "feat", always lowercase
+	Scope       string // The optional scope e.g. "cli" in "feat(cli): ..."
+	Description string // The description following the type and scope
+	Body        string // Everything after the header, including any footers
+	Breaking    bool   // Whether the commit is a breaking change
+}
+
+// Parse parses a full commit message into a conventional Commit, returning
+// [ErrNotConventional] if the message header does not match the spec.
+func Parse(message string) (Commit, error) {
+	message = strings.TrimSpace(message)
+	first, body, _ := strings.Cut(message, "\n")
+
+	match := header.FindStringSubmatch(strings.TrimSpace(first))
+	if match == nil {
+		return Commit{}, ErrNotConventional
+	}
+
+	commit := Commit{
+		Type:        strings.ToLower(match[header.SubexpIndex("type")]),
+		Scope:       match[header.SubexpIndex("scope")],
+		Description: strings.TrimSpace(match[header.SubexpIndex("description")]),
+		Body:        strings.TrimSpace(body),
+		Breaking:    match[header.SubexpIndex("breaking")] == "!",
+	}
+
+	for line := range strings.Lines(commit.Body) {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			commit.Breaking = true
+			break
+		}
+	}
+
+	return commit, nil
+}
+
+// IsFeature reports whether the commit adds a new feature.
+func (c Commit) IsFeature() bool {
+	return c.Type == "feat"
+}
+
+// IsFix reports whether the commit fixes a bug.
+func (c Commit) IsFix() bool {
+	return c.Type == "fix"
+}
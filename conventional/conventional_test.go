@@ -0,0 +1,86 @@
+package conventional_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.followtheprocess.codes/tag/conventional"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    conventional.Commit
+		wantErr bool
+	}{
+		{
+			name:    "simple feat",
+			message: "feat: Add a thing",
+			want:    conventional.Commit{Type: "feat", Description: "Add a thing"},
+		},
+		{
+			name:    "scoped fix",
+			message: "fix(cli): Handle missing flag\n",
+			want:    conventional.Commit{Type: "fix", Scope: "cli", Description: "Handle missing flag"},
+		},
+		{
+			name:    "bang",
+			message: "refactor(config)!: Rename the version key",
+			want:    conventional.Commit{Type: "refactor", Scope: "config", Description: "Rename the version key", Breaking: true},
+		},
+		{
+			name:    "breaking footer",
+			message: "feat: New config format\n\nSome details\n\nBREAKING CHANGE: The old format is gone",
+			want: conventional.Commit{
+				Type:        "feat",
+				Description: "New config format",
+				Body:        "Some details\n\nBREAKING CHANGE: The old format is gone",
+				Breaking:    true,
+			},
+		},
+		{
+			name:    "breaking footer hyphen",
+			message: "fix: Thing\n\nBREAKING-CHANGE: Oops",
+			want:    conventional.Commit{Type: "fix", Description: "Thing", Body: "BREAKING-CHANGE: Oops", Breaking: true},
+		},
+		{
+			name:    "uppercase type",
+			message: "FEAT: Shouty",
+			want:    conventional.Commit{Type: "feat", Description: "Shouty"},
+		},
+		{
+			name:    "not conventional",
+			message: "Bump version 0.1.0 -> 0.2.0",
+			wantErr: true,
+		},
+		{
+			name:    "missing space",
+			message: "feat:no space",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			message: "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := conventional.Parse(tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, conventional.ErrNotConventional) {
+				t.Errorf("Expected ErrNotConventional, got %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", got, tt.want)
+			}
+		})
+	}
+}
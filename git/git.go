@@ -1,35 +1,114 @@
 // Package git implements tags interface with git in order to interact
 // with tags and make commits.
+//
+// Every git command is run with a context, if it's cancelled (e.g. by Ctrl-C)
+// the command is killed and returns an error.
 package git
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"os/exec"
+	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
 var (
-	gitCommand     = exec.Command                // An internal reassignment of exec.Command for testing
 	ErrNoTagsFound = errors.New("no tags found") // ErrNoTagsFound is the signal that the current repo has no tags
+
+	// signers pull who signed a tag out of the git verify-tag output, for gpg
+	// and ssh signatures respectively.
+	signers = []*regexp.Regexp{
+		regexp.MustCompile(`Good signature from "([^"]+)"`),
+		regexp.MustCompile(`Good "git" signature for (\S+)`),
+	}
 )
 
+const (
+	fieldSeparator  = "\x1f" // ASCII unit separator, separates fields in git log output
+	recordSeparator = "\x1e" // ASCII record separator, separates commits in git log output
+)
+
+// LogEntry is a single commit from the git log.
+type LogEntry struct {
+	Hash    string // The full commit hash
+	Message string // The full commit message, subject and body
+}
+
+// TagInfo is the detail of a single tag.
+type TagInfo struct {
+	Date    time.Time // When the tag (or the commit, for a lightweight tag) was made
+	Name    string    // The tag name e.g. "v1.2.3"
+	Commit  string    // Full hash of the tagged commit
+	Message string    // The tag message (or the commit message, for a lightweight tag)
+}
+
+// Signing is how to sign a commit or tag.
+type Signing struct {
+	Key    string // The key to sign with, empty means git's user.signingkey
+	Format string // One of git's gpg.format values ("openpgp", "x509" or "ssh"), empty means git's default
+}
+
+// config returns the git options to pass before the subcommand to sign in the right format.
+func (s Signing) config() []string {
+	if s.Format == "" {
+		return nil
+	}
+	return []string{"-c", "gpg.format=" + s.Format}
+}
+
+// Verification is the result of checking the signature on a tag.
+type Verification struct {
+	Signer string // Who signed the tag e.g. "Tag Test <tag@test.com>", empty if it couldn't be worked out
+	Output string // The raw output from gpg or ssh-keygen
+}
+
+// tagFormat is the git for-each-ref format used to get a TagInfo, *objectname is
+// the tagged commit for annotated tags, and empty for lightweight ones.
+const tagFormat = "--format=%(refname:short)%1f%(objectname)%1f%(*objectname)%1f%(creatordate:iso-strict)%1f%(contents:subject)%1e"
+
+// Subject returns the first line of the commit message.
+func (c LogEntry) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return strings.TrimSpace(subject)
+}
+
 // Commit performs a git commit with a message.
-func Commit(message string) (string, error) {
-	cmd := gitCommand("git", "commit", "-m", message)
+func (e Exec) Commit(ctx context.Context, message string) (string, error) {
+	cmd := e.command(ctx, "commit", "-m", message)
 	out, err := cmd.CombinedOutput()
 	return string(out), err
 }
 
-// Add stages all files.
-func Add() error {
-	cmd := gitCommand("git", "add", "-A")
+// SignedCommit performs a signed git commit with a message.
+func (e Exec) SignedCommit(ctx context.Context, message string, signing Signing) (string, error) {
+	sign := "--gpg-sign"
+	if signing.Key != "" {
+		sign += "=" + signing.Key
+	}
+	args := append(signing.config(), "commit", sign, "-m", message)
+	cmd := e.command(ctx, args...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// Add stages all files, so as well as paths, anything a hook changed or created
+// is staged too.
+func (e Exec) Add(ctx context.Context, _ ...string) error {
+	cmd := e.command(ctx, "add", "-A")
 	return cmd.Run()
 }
 
-// Push performs a git push to the configured remote.
-func Push() (string, error) {
-	cmd := gitCommand("git", "push", "--follow-tags", "--atomic")
+// Push pushes refs to remote in one atomic push, so either all of them make it or none do.
+//
+// Refs are pushed exactly as given e.g. "refs/tags/v1.2.3", so a lightweight tag
+// is pushed just the same as an annotated one and nothing else comes along with it.
+func (e Exec) Push(ctx context.Context, remote string, refs ...string) (string, error) {
+	args := append([]string{"push", "--atomic", remote}, refs...)
+	cmd := e.command(ctx, args...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return string(out), err
@@ -37,25 +116,95 @@ func Push() (string, error) {
 	return string(out), nil
 }
 
-// ListTags lists all tags in descending order (latest at the top).
-func ListTags(limit int) (tags string, limitHit bool, err error) {
+// ListTags lists all the version tags in descending order (latest at the top).
+//
+// Only tags of the form <prefix>v<version> are listed e.g. a prefix of "api/"
+// lists "api/v1.2.3", and an empty prefix lists "v1.2.3" but not a module's tags.
+func (e Exec) ListTags(ctx context.Context, prefix string, limit int) (tags string, limitHit bool, err error) {
+	args := []string{"tag", "--sort=-version:refname", "--list", tagPattern(prefix)}
 	// git will return nothing if there are no tags
-	cmd := gitCommand("git", "tag", "--sort=-version:refname")
+	cmd := e.command(ctx, args...)
 	out, err := cmd.CombinedOutput()
 	if bytes.Equal(out, []byte("")) {
 		return "", false, ErrNoTagsFound
 	}
+	tags, limitHit = limitLines(out, limit)
+	return tags, limitHit, err
+}
+
+// limitLines returns at most limit lines of out, and whether any were cut off.
+func limitLines(out []byte, limit int) (string, bool) {
+	limitHit := false
 	lines := bytes.Split(out, []byte("\n"))
 	if len(lines) > limit {
 		limitHit = true
 		lines = lines[:limit]
 	}
-	return string(bytes.Join(lines, []byte("\n"))), limitHit, err
+	return string(bytes.Join(lines, []byte("\n"))), limitHit
 }
 
-// LatestTag returns the name of the latest tag.
-func LatestTag() (string, error) {
-	cmd := gitCommand("git", "describe", "--tags", "--abbrev=0")
+// Tags returns the detail of all the version tags in descending order (latest first).
+//
+// Only tags of the form <prefix>v<version> are returned, as for [Exec.ListTags].
+func (e Exec) Tags(ctx context.Context, prefix string) ([]TagInfo, error) {
+	return e.tagInfo(ctx, []string{"tag", "--list", "--sort=-version:refname", tagFormat, tagPattern(prefix)})
+}
+
+// Tag returns the detail of a single tag.
+func (e Exec) Tag(ctx context.Context, name string) (TagInfo, error) {
+	tags, err := e.tagInfo(ctx, []string{"tag", "--list", tagFormat, name})
+	if err != nil {
+		return TagInfo{}, err
+	}
+	if len(tags) == 0 {
+		return TagInfo{}, fmt.Errorf("tag %s not found", name)
+	}
+	return tags[0], nil
+}
+
+// tagInfo is a helper that runs git with args, which must include tagFormat,
+// and parses the output.
+func (e Exec) tagInfo(ctx context.Context, args []string) ([]TagInfo, error) {
+	cmd := e.command(ctx, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("could not list tags: %s", strings.TrimSpace(string(out)))
+	}
+
+	var tags []TagInfo
+	for record := range strings.SplitSeq(string(out), recordSeparator) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		fields := strings.Split(record, fieldSeparator)
+		if len(fields) != 5 { //nolint: mnd // The fields in tagFormat
+			return nil, fmt.Errorf("malformed git tag output: %q", record)
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("bad date for tag %s: %w", fields[0], err)
+		}
+
+		commit := fields[2]
+		if commit == "" {
+			// Lightweight tag, it points straight at the commit
+			commit = fields[1]
+		}
+
+		tags = append(tags, TagInfo{Name: fields[0], Commit: commit, Date: date, Message: fields[4]})
+	}
+
+	return tags, nil
+}
+
+// LatestTag returns the name of the latest version tag.
+//
+// Only tags of the form <prefix>v<version> are considered, as for [Exec.ListTags].
+func (e Exec) LatestTag(ctx context.Context, prefix string) (string, error) {
+	cmd := e.command(ctx, "describe", "--tags", "--abbrev=0", "--match", tagPattern(prefix))
 	out, err := cmd.CombinedOutput()
 	if bytes.Contains(out, []byte("fatal: No names found")) {
 		return "", ErrNoTagsFound
@@ -63,20 +212,242 @@ func LatestTag() (string, error) {
 	return strings.TrimSpace(string(out)), err
 }
 
+// CommitsSince returns all the commits reachable from HEAD but not from ref, newest first.
+//
+// If ref is empty, all commits reachable from HEAD are returned. If any paths are
+// given, only commits touching those paths are returned.
+func (e Exec) CommitsSince(ctx context.Context, ref string, paths ...string) ([]LogEntry, error) {
+	args := []string{"log", "--format=%H%x1f%B%x1e"}
+	if ref != "" {
+		args = append(args, ref+"..HEAD")
+	}
+	if len(paths) != 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+	cmd := e.command(ctx, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("could not get commits since %q: %s", ref, strings.TrimSpace(string(out)))
+	}
+
+	var commits []LogEntry
+	for record := range strings.SplitSeq(string(out), recordSeparator) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		hash, message, ok := strings.Cut(record, fieldSeparator)
+		if !ok {
+			return nil, fmt.Errorf("malformed git log output: %q", record)
+		}
+		commits = append(commits, LogEntry{Hash: hash, Message: strings.TrimSpace(message)})
+	}
+
+	return commits, nil
+}
+
+// ListFiles lists all the files under the repo's directory that git knows about, or
+// could know about, that is tracked files plus any untracked files not ignored by .gitignore.
+//
+// The paths are relative to the repo's directory and always use forward slashes.
+func (e Exec) ListFiles(ctx context.Context) ([]string, error) {
+	cmd := e.command(ctx, "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+	// Not CombinedOutput, any warnings on stderr would end up in the file list
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not list files: %w", err)
+	}
+
+	var files []string
+	for file := range strings.SplitSeq(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	// Files with merge conflicts are listed once per stage
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// tagPattern returns the glob git uses to match the version tags under prefix,
+// with an empty prefix it only matches the root project's tags, not the modules'.
+func tagPattern(prefix string) string {
+	return prefix + "v[0-9]*"
+}
+
 // CreateTag creates an annotated git tag with an optional message
 // if the message is an empty string, the tag name will be used.
-func CreateTag(tag, message string) (string, error) {
+func (e Exec) CreateTag(ctx context.Context, tag, message string) (string, error) {
 	if message == "" {
 		message = tag
 	}
-	cmd := gitCommand("git", "tag", "-a", tag, "-m", message)
+	cmd := e.command(ctx, "tag", "-a", tag, "-m", message)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// SignedTag creates a signed, annotated git tag with an optional message
+// if the message is an empty string, the tag name will be used.
+func (e Exec) SignedTag(ctx context.Context, tag, message string, signing Signing) (string, error) {
+	if message == "" {
+		message = tag
+	}
+	args := signing.config()
+	if signing.Key != "" {
+		args = append(args, "tag", "--local-user", signing.Key, tag, "-m", message)
+	} else {
+		args = append(args, "tag", "--sign", tag, "-m", message)
+	}
+	cmd := e.command(ctx, args...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// VerifyTag checks the signature on tag, returning an error if it isn't signed
+// or the signature is bad.
+func (e Exec) VerifyTag(ctx context.Context, tag string) (Verification, error) {
+	cmd := e.command(ctx, "verify-tag", tag)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		return Verification{Output: output}, fmt.Errorf("could not verify tag %s: %s", tag, output)
+	}
+
+	verification := Verification{Output: output}
+	for _, pattern := range signers {
+		if match := pattern.FindStringSubmatch(output); match != nil {
+			verification.Signer = match[1]
+			break
+		}
+	}
+
+	return verification, nil
+}
+
+// TagMessage returns the message of an annotated tag, without any signature.
+func (e Exec) TagMessage(ctx context.Context, tag string) (string, error) {
+	cmd := e.command(ctx, "tag", "--list", "--format=%(contents:subject)%1f%(contents:body)", tag)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("could not get message of tag %s: %s", tag, strings.TrimSpace(string(out)))
+	}
+	subject, body, _ := strings.Cut(strings.TrimSpace(string(out)), fieldSeparator)
+	if body = strings.TrimSpace(body); body != "" {
+		return subject + "\n\n" + body, nil
+	}
+	return subject, nil
+}
+
+// DeleteTag deletes a local tag.
+func (e Exec) DeleteTag(ctx context.Context, tag string) (string, error) {
+	cmd := e.command(ctx, "tag", "--delete", tag)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// RevParse returns the full hash of the commit ref points to, for an annotated
+// tag this is the commit that was tagged, not the tag object itself.
+func (e Exec) RevParse(ctx context.Context, ref string) (string, error) {
+	cmd := e.command(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("could not resolve %s to a commit", ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitMessage returns the full message of the commit ref points to.
+func (e Exec) CommitMessage(ctx context.Context, ref string) (string, error) {
+	cmd := e.command(ctx, "log", "-1", "--format=%B", ref)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("could not get commit message of %s: %s", ref, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Show returns the contents of the file at path (relative to the repo's directory)
+// as of the commit ref points to.
+func (e Exec) Show(ctx context.Context, ref, path string) ([]byte, error) {
+	cmd := e.command(ctx, "show", ref+":./"+path)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not show %s at %s: %w", path, ref, err)
+	}
+	return out, nil
+}
+
+// Reset moves the current branch back to ref, undoing the commits after it as
+// well as their changes to the working tree.
+//
+// It uses --keep so any uncommitted changes are preserved, git refuses to reset
+// rather than overwrite them.
+func (e Exec) Reset(ctx context.Context, ref string) (string, error) {
+	cmd := e.command(ctx, "reset", "--keep", ref)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// ResetMixed moves the current branch back to ref and resets the index to match,
+// but leaves the working tree untouched.
+func (e Exec) ResetMixed(ctx context.Context, ref string) (string, error) {
+	cmd := e.command(ctx, "reset", "--mixed", "--quiet", ref)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// DefaultRemote returns the remote a plain git push would use, that is the
+// remote of the current branch if it has one, otherwise "origin".
+//
+// If the repo has no remotes at all, an empty string is returned.
+func (e Exec) DefaultRemote(ctx context.Context) (string, error) {
+	cmd := e.command(ctx, "remote")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("could not list remotes: %s", strings.TrimSpace(string(out)))
+	}
+	remotes := strings.Fields(string(out))
+	if len(remotes) == 0 {
+		return "", nil
+	}
+
+	branch, err := e.Branch(ctx)
+	if err == nil {
+		cmd = e.command(ctx, "config", "--get", "branch."+branch+".remote")
+		// Not an error if the branch has no remote, we fall back below
+		if out, err := cmd.CombinedOutput(); err == nil && len(bytes.TrimSpace(out)) != 0 {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+
+	if slices.Contains(remotes, "origin") {
+		return "origin", nil
+	}
+	return remotes[0], nil
+}
+
+// RemoteHasTag reports whether the remote has the given tag.
+func (e Exec) RemoteHasTag(ctx context.Context, remote, tag string) (bool, error) {
+	cmd := e.command(ctx, "ls-remote", "--tags", remote, "refs/tags/"+tag)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("could not list tags on %s: %s", remote, strings.TrimSpace(string(out)))
+	}
+	return len(bytes.TrimSpace(out)) != 0, nil
+}
+
+// DeleteRemoteTag deletes a tag from the remote.
+func (e Exec) DeleteRemoteTag(ctx context.Context, remote, tag string) (string, error) {
+	cmd := e.command(ctx, "push", "--delete", remote, "refs/tags/"+tag)
 	out, err := cmd.CombinedOutput()
 	return string(out), err
 }
 
 // IsRepo detects whether or not we are currently in a git repo.
-func IsRepo() bool {
-	cmd := gitCommand("git", "rev-parse", "--is-inside-work-tree")
+func (e Exec) IsRepo(ctx context.Context) bool {
+	cmd := e.command(ctx, "rev-parse", "--is-inside-work-tree")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return false
@@ -88,8 +459,8 @@ func IsRepo() bool {
 }
 
 // Branch gets the name of the current git branch.
-func Branch() (string, error) {
-	cmd := gitCommand("git", "rev-parse", "--abbrev-ref", "HEAD")
+func (e Exec) Branch(ctx context.Context) (string, error) {
+	cmd := e.command(ctx, "rev-parse", "--abbrev-ref", "HEAD")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return string(out), err
@@ -98,8 +469,8 @@ func Branch() (string, error) {
 }
 
 // IsDirty checks whether or not the working tree is dirty.
-func IsDirty() (bool, error) {
-	cmd := gitCommand("git", "status", "--porcelain")
+func (e Exec) IsDirty(ctx context.Context) (bool, error) {
+	cmd := e.command(ctx, "status", "--porcelain")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return false, err
@@ -1,11 +1,17 @@
 package git //nolint: testpackage // We need access to internals to mock os.Exec
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -13,10 +19,10 @@ var (
 	mockStdout     string
 )
 
-func fakeExecCommand(command string, args ...string) *exec.Cmd {
+func fakeExecCommand(ctx context.Context, command string, args ...string) *exec.Cmd {
 	cs := []string{"-test.run=TestExecCommandHelper", "--", command}
 	cs = append(cs, args...)
-	cmd := exec.Command(os.Args[0], cs...)
+	cmd := exec.CommandContext(ctx, os.Args[0], cs...)
 	es := strconv.Itoa(mockExitStatus)
 	cmd.Env = []string{
 		"GO_WANT_HELPER_PROCESS=1",
@@ -73,12 +79,11 @@ func TestCommit(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			out, err := Commit("Bump version 0.1.0 -> 0.2.0")
+			out, err := repo.Commit(t.Context(), "Bump version 0.1.0 -> 0.2.0")
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("Commit() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.Commit(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 
 			if out != tt.stdout {
@@ -113,12 +118,11 @@ func TestAdd(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			err := Add()
+			err := repo.Add(t.Context())
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("Add() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.Add(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 		})
 	}
@@ -149,12 +153,11 @@ func TestPush(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			out, err := Push()
+			out, err := repo.Push(t.Context(), "origin", "refs/tags/v0.1.0")
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("Push() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.Push(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 
 			if out != tt.stdout {
@@ -198,12 +201,11 @@ func TestListTags(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			out, _, err := ListTags(10)
+			out, _, err := repo.ListTags(t.Context(), "", 10)
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("ListTags() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.ListTags(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 
 			if out != tt.stdout {
@@ -238,12 +240,11 @@ func TestLatestTag(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			out, err := LatestTag()
+			out, err := repo.LatestTag(t.Context(), "")
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("LatestTag() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.LatestTag(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 
 			if out != tt.stdout {
@@ -278,12 +279,11 @@ func TestCreateTag(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			out, err := CreateTag("v1.4.5", "This is a tag")
+			out, err := repo.CreateTag(t.Context(), "v1.4.5", "This is a tag")
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("CreateTag() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.CreateTag(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 
 			if out != tt.stdout {
@@ -324,10 +324,9 @@ func TestIsRepo(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			if got := IsRepo(); got != tt.want {
+			if got := repo.IsRepo(t.Context()); got != tt.want {
 				t.Errorf("IsRepo returned %v, wanted %v", got, tt.want)
 			}
 		})
@@ -365,12 +364,11 @@ func TestIsDirty(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			got, err := IsDirty()
+			got, err := repo.IsDirty(t.Context())
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("IsDirty() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.IsDirty(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 
 			if got != tt.want {
@@ -422,16 +420,730 @@ func TestBranch(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockExitStatus = tt.status
 			mockStdout = tt.stdout
-			gitCommand = fakeExecCommand
-			defer func() { gitCommand = exec.Command }()
+			repo := Exec{commandContext: fakeExecCommand}
 
-			got, err := Branch()
+			got, err := repo.Branch(t.Context())
 			if (err != nil) != tt.wantErr {
-				t.Fatalf("Branch() returned %v, wanted %v", err, tt.wantErr)
+				t.Fatalf("Exec{}.Branch(t.Context()) returned %v, wanted %v", err, tt.wantErr)
 			}
 
 			if got != tt.want {
-				t.Errorf("Branch() returned %s, wanted %s", got, tt.want)
+				t.Errorf("Exec{}.Branch(t.Context()) returned %s, wanted %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitsSince(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		want    []LogEntry
+		status  int
+		wantErr bool
+	}{
+		{
+			name:   "happy",
+			stdout: "abc123\x1ffeat: Add a thing\n\nWith a body\n\x1e\ndef456\x1ffix: Fix a thing\n\x1e\n",
+			want: []LogEntry{
+				{Hash: "abc123", Message: "feat: Add a thing\n\nWith a body"},
+				{Hash: "def456", Message: "fix: Fix a thing"},
+			},
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "empty",
+			stdout:  "",
+			want:    nil,
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "sad",
+			stdout:  "fatal: bad revision",
+			want:    nil,
+			status:  128,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			got, err := repo.CommitsSince(t.Context(), "v0.1.0")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.CommitsSince(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Exec{}.CommitsSince(t.Context()) got %#v, wanted %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		want    []string
+		status  int
+		wantErr bool
+	}{
+		{
+			// Can't pass NUL through the fake command's environment so
+			// multiple files are covered in the app tests against a real repo
+			name:    "happy",
+			stdout:  "docs/a.md",
+			want:    []string{"docs/a.md"},
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "empty",
+			stdout:  "",
+			want:    nil,
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "sad",
+			stdout:  "fatal: not a git repository",
+			want:    nil,
+			status:  128,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			got, err := repo.ListFiles(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.ListFiles(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Exec{}.ListFiles(t.Context()) got %#v, wanted %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		want    string
+		status  int
+		wantErr bool
+	}{
+		{
+			name:    "happy",
+			stdout:  "8a5d1c3e2f4b6a7d9e0f1a2b3c4d5e6f7a8b9c0d\n",
+			status:  0,
+			want:    "8a5d1c3e2f4b6a7d9e0f1a2b3c4d5e6f7a8b9c0d",
+			wantErr: false,
+		},
+		{
+			name:    "sad",
+			stdout:  "",
+			status:  1,
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			got, err := repo.RevParse(t.Context(), "v1.2.3")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.RevParse(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Exec{}.RevParse(t.Context()) returned %s, wanted %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteHasTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		status  int
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "pushed",
+			stdout:  "8a5d1c3e2f4b6a7d9e0f1a2b3c4d5e6f7a8b9c0d\trefs/tags/v1.2.3\n",
+			status:  0,
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "not pushed",
+			stdout:  "",
+			status:  0,
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "sad",
+			stdout:  "fatal: could not read from remote repository",
+			status:  128,
+			want:    false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			got, err := repo.RemoteHasTag(t.Context(), "origin", "v1.2.3")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.RemoteHasTag(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Exec{}.RemoteHasTag(t.Context()) returned %v, wanted %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	date := time.Date(2026, time.March, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		stdout  string
+		want    []TagInfo
+		status  int
+		wantErr bool
+	}{
+		{
+			name: "happy",
+			stdout: "v0.2.0\x1ftagobject\x1fcommit2\x1f2026-03-14T09:30:00Z\x1fRelease v0.2.0\x1e\n" +
+				"v0.1.0\x1fcommit1\x1f\x1f2026-03-14T09:30:00Z\x1fLightweight\x1e\n",
+			want: []TagInfo{
+				{Name: "v0.2.0", Commit: "commit2", Date: date, Message: "Release v0.2.0"},
+				{Name: "v0.1.0", Commit: "commit1", Date: date, Message: "Lightweight"},
+			},
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "malformed",
+			stdout:  "v0.1.0\x1fcommit1\x1e\n",
+			status:  0,
+			wantErr: true,
+		},
+		{
+			name:    "sad",
+			stdout:  "I failed!",
+			status:  1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			got, err := repo.Tags(t.Context(), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.Tags(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		signing Signing
+		status  int
+		wantErr bool
+	}{
+		{
+			name:    "default key",
+			stdout:  "success",
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "ssh key",
+			stdout:  "success",
+			signing: Signing{Key: "~/.ssh/id_ed25519", Format: "ssh"},
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "sad",
+			stdout:  "error: gpg failed to sign the data",
+			status:  1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			out, err := repo.SignedCommit(t.Context(), "Bump version 0.1.0 -> 0.2.0", tt.signing)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.SignedCommit(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if out != tt.stdout {
+				t.Errorf("SignedCommit stdout was %q, wanted %q", out, tt.stdout)
+			}
+		})
+	}
+}
+
+func TestSignedTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		signing Signing
+		status  int
+		wantErr bool
+	}{
+		{
+			name:    "default key",
+			stdout:  "Woohoo tag created",
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "specific key",
+			stdout:  "Woohoo tag created",
+			signing: Signing{Key: "ABCDEF0123456789", Format: "openpgp"},
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "sad",
+			stdout:  "error: gpg failed to sign the data",
+			status:  1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			out, err := repo.SignedTag(t.Context(), "v1.4.5", "This is a tag", tt.signing)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.SignedTag(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if out != tt.stdout {
+				t.Errorf("SignedTag stdout was %q, wanted %q", out, tt.stdout)
+			}
+		})
+	}
+}
+
+func TestVerifyTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		want    string
+		status  int
+		wantErr bool
+	}{
+		{
+			name: "gpg",
+			stdout: `gpg: Signature made Sat 14 Mar 2026 09:30:00 GMT
+gpg:                using EDDSA key ABCDEF0123456789
+gpg: Good signature from "Tag Test <tagtest@gmail.com>" [ultimate]`,
+			want:    "Tag Test <tagtest@gmail.com>",
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "ssh",
+			stdout:  `Good "git" signature for tagtest@gmail.com with ED25519 key SHA256:abc123`,
+			want:    "tagtest@gmail.com",
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "unknown signer",
+			stdout:  "Looks good to me",
+			want:    "",
+			status:  0,
+			wantErr: false,
+		},
+		{
+			name:    "not signed",
+			stdout:  "error: no signature found",
+			status:  1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockExitStatus = tt.status
+			mockStdout = tt.stdout
+			repo := Exec{commandContext: fakeExecCommand}
+
+			got, err := repo.VerifyTag(t.Context(), "v1.2.3")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Exec{}.VerifyTag(t.Context()) returned %v, wanted %v", err, tt.wantErr)
+			}
+
+			if got.Signer != tt.want {
+				t.Errorf("Got signer %q, wanted %q", got.Signer, tt.want)
+			}
+		})
+	}
+}
+
+// run is a helper that runs git in dir, failing the test if it errors.
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v returned an error: %s", args, out)
+	}
+	return string(out)
+}
+
+// nativeRepo creates a git repo with some history and tags to compare the
+// [Native] and [Exec] implementations against.
+func nativeRepo(t *testing.T) string {
+	t.Helper()
+	tmp := t.TempDir()
+
+	t.Setenv("GIT_AUTHOR_NAME", "Tag Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "tagtest@gmail.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Tag Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "tagtest@gmail.com")
+
+	run(t, tmp, "init", "--quiet", "--initial-branch=main")
+	run(t, tmp, "remote", "add", "origin", "https://example.com/repo.git")
+
+	write := func(name, contents string) {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+	}
+
+	// Spread the commits out so the order is well defined
+	date := time.Date(2026, time.March, 14, 9, 30, 0, 0, time.FixedZone("", 3600))
+	commit := func(message string) {
+		date = date.Add(time.Hour)
+		t.Setenv("GIT_COMMITTER_DATE", date.Format(time.RFC3339))
+		t.Setenv("GIT_AUTHOR_DATE", date.Format(time.RFC3339))
+		run(t, tmp, "add", "-A")
+		run(t, tmp, "commit", "--quiet", "-m", message)
+	}
+
+	write("README.md", "Hello, version 0.1.0\n")
+	commit("feat: initial")
+	run(t, tmp, "tag", "-a", "v0.1.0", "-m", "Release 0.1.0\n\nWith a body")
+
+	write("api/api.go", "package api\n")
+	commit("feat(api): add api\n\nWith a body too")
+	run(t, tmp, "tag", "api/v0.1.0")
+
+	write("README.md", "Hello, version 0.2.0\n")
+	commit("fix: typo")
+	run(t, tmp, "tag", "-a", "v0.2.0", "-m", "v0.2.0")
+
+	write("api/api.go", "package api\n\nconst Version = \"0.2.0\"\n")
+	commit("chore(api): more api")
+	run(t, tmp, "tag", "-a", "v0.10.0", "-m", "Ten")
+
+	write("docs/index.md", "# Docs\n")
+	commit("docs: after the tag")
+
+	return tmp
+}
+
+func TestNative(t *testing.T) {
+	tmp := nativeRepo(t)
+	t.Chdir(tmp)
+
+	var (
+		want = Exec{}
+		got  = NewNative(tmp)
+	)
+
+	// equal is a helper that checks the native and exec results of the same call match
+	equal := func(t *testing.T, name string, got, want any, gotErr, wantErr error) {
+		t.Helper()
+		if (gotErr != nil) != (wantErr != nil) {
+			t.Fatalf("%s: native returned error %v, exec returned %v", name, gotErr, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\nGot:\n%#v\n\nWanted:\n%#v\n", name, got, want)
+		}
+	}
+
+	// utc normalises the dates so the time zones compare equal
+	utc := func(tags []TagInfo) []TagInfo {
+		for i := range tags {
+			tags[i].Date = tags[i].Date.UTC()
+		}
+		return tags
+	}
+
+	compare := func(t *testing.T) {
+		t.Helper()
+		if !got.IsRepo(t.Context()) {
+			t.Fatal("IsRepo: expected true")
+		}
+
+		gotBranch, gotErr := got.Branch(t.Context())
+		wantBranch, wantErr := want.Branch(t.Context())
+		equal(t, "Branch", gotBranch, wantBranch, gotErr, wantErr)
+
+		gotFiles, gotErr := got.ListFiles(t.Context())
+		wantFiles, wantErr := want.ListFiles(t.Context())
+		equal(t, "ListFiles", gotFiles, wantFiles, gotErr, wantErr)
+
+		for _, prefix := range []string{"", "api/", "missing/"} {
+			gotTags, gotHit, gotErr := got.ListTags(t.Context(), prefix, 2)
+			wantTags, wantHit, wantErr := want.ListTags(t.Context(), prefix, 2)
+			equal(t, "ListTags "+prefix, []any{gotTags, gotHit}, []any{wantTags, wantHit}, gotErr, wantErr)
+
+			gotInfo, gotErr := got.Tags(t.Context(), prefix)
+			wantInfo, wantErr := want.Tags(t.Context(), prefix)
+			equal(t, "Tags "+prefix, utc(gotInfo), utc(wantInfo), gotErr, wantErr)
+
+			gotLatest, gotErr := got.LatestTag(t.Context(), prefix)
+			wantLatest, wantErr := want.LatestTag(t.Context(), prefix)
+			equal(t, "LatestTag "+prefix, gotLatest, wantLatest, gotErr, wantErr)
+		}
+
+		for _, tag := range []string{"v0.1.0", "api/v0.1.0", "v0.10.0"} {
+			gotMessage, gotErr := got.TagMessage(t.Context(), tag)
+			wantMessage, wantErr := want.TagMessage(t.Context(), tag)
+			equal(t, "TagMessage "+tag, gotMessage, wantMessage, gotErr, wantErr)
+		}
+
+		for _, ref := range []string{"HEAD", "HEAD~1", "HEAD^", "HEAD~3^{commit}", "main", "v0.1.0", "api/v0.1.0", "nope", "HEAD~10"} {
+			gotHash, gotErr := got.RevParse(t.Context(), ref)
+			wantHash, wantErr := want.RevParse(t.Context(), ref)
+			equal(t, "RevParse "+ref, gotHash, wantHash, gotErr, wantErr)
+
+			gotMessage, gotErr := got.CommitMessage(t.Context(), ref)
+			wantMessage, wantErr := want.CommitMessage(t.Context(), ref)
+			equal(t, "CommitMessage "+ref, gotMessage, wantMessage, gotErr, wantErr)
+		}
+
+		for _, since := range [][]string{{""}, {"v0.1.0"}, {"v0.1.0", "api"}, {"", "README.md"}, {"", "missing"}} {
+			gotCommits, gotErr := got.CommitsSince(t.Context(), since[0], since[1:]...)
+			wantCommits, wantErr := want.CommitsSince(t.Context(), since[0], since[1:]...)
+			equal(t, fmt.Sprintf("CommitsSince %v", since), gotCommits, wantCommits, gotErr, wantErr)
+		}
+
+		gotShow, gotErr := got.Show(t.Context(), "HEAD~2", "README.md")
+		wantShow, wantErr := want.Show(t.Context(), "HEAD~2", "README.md")
+		equal(t, "Show", gotShow, wantShow, gotErr, wantErr)
+
+		gotRemote, gotErr := got.DefaultRemote(t.Context())
+		wantRemote, wantErr := want.DefaultRemote(t.Context())
+		equal(t, "DefaultRemote", gotRemote, wantRemote, gotErr, wantErr)
+	}
+
+	t.Run("loose", compare)
+
+	// Packs everything, including the refs
+	run(t, tmp, "gc", "--quiet", "--aggressive")
+	if _, err := os.Stat(filepath.Join(tmp, ".git", "packed-refs")); err != nil {
+		t.Fatalf("Expected gc to pack the refs: %v", err)
+	}
+	got = NewNative(tmp)
+
+	t.Run("packed", compare)
+
+	t.Run("dirty", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(tmp, "README.md"), []byte("Changed"), 0o644); err != nil {
+			t.Fatalf("could not write README.md: %v", err)
+		}
+		compare(t)
+
+		run(t, tmp, "add", "README.md")
+		compare(t)
+
+		run(t, tmp, "reset", "--quiet", "--hard")
+		compare(t)
+	})
+
+	t.Run("subdirectory", func(t *testing.T) {
+		api := NewNative(filepath.Join(tmp, "api"))
+
+		files, err := api.ListFiles(t.Context())
+		if err != nil {
+			t.Fatalf("ListFiles returned an error: %v", err)
+		}
+		if !reflect.DeepEqual(files, []string{"api.go"}) {
+			t.Errorf("Wrong files in subdirectory: %v", files)
+		}
+
+		contents, err := api.Show(t.Context(), "HEAD", "api.go")
+		if err != nil {
+			t.Fatalf("Show returned an error: %v", err)
+		}
+		if string(contents) != "package api\n\nconst Version = \"0.2.0\"\n" {
+			t.Errorf("Wrong contents: %q", contents)
+		}
+	})
+
+	t.Run("split index", func(t *testing.T) {
+		run(t, tmp, "update-index", "--split-index")
+		defer run(t, tmp, "update-index", "--no-split-index")
+
+		// The shared index has the files, so reading just the split one would miss them
+		if _, err := got.ListFiles(t.Context()); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported from ListFiles with a split index, got %v", err)
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		before, err := want.RevParse(t.Context(), "HEAD")
+		if err != nil {
+			t.Fatalf("RevParse returned an error: %v", err)
+		}
+
+		if _, err := got.IsDirty(t.Context()); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported from IsDirty, got %v", err)
+		}
+		if err := got.Add(t.Context()); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported from Add, got %v", err)
+		}
+
+		writes := map[string]func() (string, error){
+			"Commit":          func() (string, error) { return got.Commit(t.Context(), "Native") },
+			"SignedCommit":    func() (string, error) { return got.SignedCommit(t.Context(), "Signed", Signing{}) },
+			"CreateTag":       func() (string, error) { return got.CreateTag(t.Context(), "v1.0.0", "Version one") },
+			"SignedTag":       func() (string, error) { return got.SignedTag(t.Context(), "v1.0.0", "Version one", Signing{}) },
+			"DeleteTag":       func() (string, error) { return got.DeleteTag(t.Context(), "v0.1.0") },
+			"Reset":           func() (string, error) { return got.Reset(t.Context(), "HEAD~1") },
+			"ResetMixed":      func() (string, error) { return got.ResetMixed(t.Context(), "HEAD~1") },
+			"Push":            func() (string, error) { return got.Push(t.Context(), "origin", "refs/tags/v0.1.0") },
+			"DeleteRemoteTag": func() (string, error) { return got.DeleteRemoteTag(t.Context(), "origin", "v0.1.0") },
+		}
+		for name, write := range writes {
+			out, err := write()
+			if !errors.Is(err, ErrUnsupported) {
+				t.Errorf("Expected ErrUnsupported from %s, got %v", name, err)
+				continue
+			}
+			if out != err.Error() {
+				t.Errorf("Expected %s to return the error as its output, got %q", name, out)
+			}
+		}
+		if _, err := got.SignedCommit(t.Context(), "Signed", Signing{}); !strings.Contains(err.Error(), "signing commits") {
+			t.Errorf("Expected SignedCommit to say signing commits is unsupported, got %v", err)
+		}
+
+		// Nothing was touched
+		if head, _ := want.RevParse(t.Context(), "HEAD"); head != before { //nolint: errcheck // Checked by comparison
+			t.Errorf("HEAD moved: got %s, wanted %s", head, before)
+		}
+		run(t, tmp, "fsck", "--strict", "--no-dangling")
+		compare(t)
+	})
+
+	t.Run("not a repo", func(t *testing.T) {
+		outside := NewNative(t.TempDir())
+		if outside.IsRepo(t.Context()) {
+			t.Error("Expected IsRepo to be false outside a repo")
+		}
+		if _, err := outside.Branch(t.Context()); err == nil {
+			t.Error("Expected an error from Branch outside a repo")
+		}
+	})
+}
+
+func TestRootTagsSkipModules(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "Tag Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "tagtest@gmail.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Tag Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "tagtest@gmail.com")
+
+	run(t, tmp, "init", "--quiet", "--initial-branch=main")
+	run(t, tmp, "commit", "--quiet", "--allow-empty", "-m", "feat: initial")
+	run(t, tmp, "tag", "-a", "v0.1.0", "-m", "v0.1.0")
+	run(t, tmp, "commit", "--quiet", "--allow-empty", "-m", "feat(api): add api")
+	run(t, tmp, "tag", "-a", "api/v0.3.0", "-m", "api/v0.3.0")
+	t.Chdir(tmp)
+
+	// The module tag is the newest, and sorts higher, but it isn't the root project's
+	for name, repo := range map[string]Repo{"exec": Exec{}, "native": NewNative(tmp)} {
+		t.Run(name, func(t *testing.T) {
+			latest, err := repo.LatestTag(t.Context(), "")
+			if err != nil {
+				t.Fatalf("LatestTag returned an error: %v", err)
+			}
+			if latest != "v0.1.0" {
+				t.Errorf("LatestTag: got %q, wanted %q", latest, "v0.1.0")
+			}
+
+			tags, _, err := repo.ListTags(t.Context(), "", 10)
+			if err != nil {
+				t.Fatalf("ListTags returned an error: %v", err)
+			}
+			if got := strings.TrimSpace(tags); got != "v0.1.0" {
+				t.Errorf("ListTags: got %q, wanted %q", got, "v0.1.0")
+			}
+
+			info, err := repo.Tags(t.Context(), "")
+			if err != nil {
+				t.Fatalf("Tags returned an error: %v", err)
+			}
+			if len(info) != 1 || info[0].Name != "v0.1.0" {
+				t.Errorf("Tags: got %v, wanted only v0.1.0", info)
+			}
+
+			module, err := repo.LatestTag(t.Context(), "api/")
+			if err != nil {
+				t.Fatalf("LatestTag for the module returned an error: %v", err)
+			}
+			if module != "api/v0.3.0" {
+				t.Errorf("LatestTag for the module: got %q, wanted %q", module, "api/v0.3.0")
 			}
 		})
 	}
@@ -0,0 +1,115 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+const (
+	indexMagic      = "DIRC"
+	indexEntryFixed = 62 // Bytes in an index entry before the path
+	indexExtension  = 8  // Bytes in an index extension header, the signature then the size
+
+	flagExtended = 0x4000 // The entry has a second set of flags
+	nameMask     = 0x0fff
+	modeDir      = 0o040000 // A sparse directory entry, standing in for everything under it
+)
+
+// readIndex returns the path of every entry in the index file at path, from the root
+// of the working tree with forward slashes. A missing index has no entries.
+//
+// The paths are as git wrote them so a file with merge conflicts is listed once
+// per stage. A split or sparse index doesn't list every file, so those are
+// [ErrUnsupported] rather than read wrong.
+func readIndex(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read index: %w", err)
+	}
+
+	if len(data) < 12+hashSize || string(data[:4]) != indexMagic { //nolint: mnd // Header size
+		return nil, errors.New("bad index file")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 { //nolint: mnd // The versions git writes
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	paths := make([]string, 0, count)
+	rest := data[12 : len(data)-hashSize]
+	previous := ""
+	for range count {
+		if len(rest) < indexEntryFixed {
+			return nil, errors.New("truncated index file")
+		}
+
+		if binary.BigEndian.Uint32(rest[24:28]) == modeDir {
+			return nil, fmt.Errorf("reading a sparse index is %w", ErrUnsupported)
+		}
+		flags := binary.BigEndian.Uint16(rest[60:62])
+
+		start := indexEntryFixed
+		if flags&flagExtended != 0 {
+			start += 2
+		}
+
+		var path string
+		if version == 4 { //nolint: mnd // Prefix compressed paths
+			r := bytes.NewReader(rest[start:])
+			strip, err := readOffset(r)
+			if err != nil || strip > int64(len(previous)) {
+				return nil, errors.New("bad path in index file")
+			}
+			consumed := len(rest[start:]) - r.Len()
+			end := bytes.IndexByte(rest[start+consumed:], 0)
+			if end < 0 {
+				return nil, errors.New("truncated index file")
+			}
+			path = previous[:len(previous)-int(strip)] + string(rest[start+consumed:start+consumed+end])
+			rest = rest[start+consumed+end+1:]
+		} else {
+			end := bytes.IndexByte(rest[start:], 0)
+			if end < 0 {
+				return nil, errors.New("truncated index file")
+			}
+			if length := int(flags & nameMask); length < nameMask && length != end {
+				return nil, errors.New("bad path in index file")
+			}
+			path = string(rest[start : start+end])
+			// Entries are padded with 1-8 NULs to a multiple of 8 bytes
+			size := (start + end + 8) &^ 7 //nolint: mnd // 8 byte alignment
+			if size > len(rest) {
+				return nil, errors.New("truncated index file")
+			}
+			rest = rest[size:]
+		}
+
+		previous = path
+		paths = append(paths, path)
+	}
+
+	// The extensions only cache things, apart from these which mean the entries above aren't all of them
+	for len(rest) >= indexExtension {
+		switch string(rest[:4]) {
+		case "link":
+			return nil, fmt.Errorf("reading a split index is %w", ErrUnsupported)
+		case "sdir":
+			return nil, fmt.Errorf("reading a sparse index is %w", ErrUnsupported)
+		}
+		size := int(binary.BigEndian.Uint32(rest[4:8]))
+		if size > len(rest)-indexExtension {
+			return nil, errors.New("truncated index file")
+		}
+		rest = rest[indexExtension+size:]
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,1141 @@
+package git
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxSymrefs is how many symbolic refs (or nested tags) to follow before giving up, same as git.
+const maxSymrefs = 5
+
+var (
+	// errRefNotFound is returned when a ref doesn't exist.
+	errRefNotFound = errors.New("ref not found")
+
+	// hexHash matches a full hex object name.
+	hexHash = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+	// signatureStart matches the first line of a signature appended to a tag message.
+	signatureStart = regexp.MustCompile(`(?m)^-----BEGIN (PGP SIGNATURE|PGP MESSAGE|SSH SIGNATURE|SIGNED MESSAGE)-----$`)
+)
+
+// Native is a [Repo] implemented in pure Go, for when the git binary isn't installed.
+//
+// It only ever reads the repository (refs, loose and packed objects and the index),
+// which is enough to list tags, find the latest and show history. Anything that
+// writes to the repo, or needs git's filters, hooks, signing or remotes to get right,
+// returns [ErrUnsupported] and is left to [Exec], so bumping and undoing need git.
+//
+// Unlike [Exec], ListFiles only lists tracked files, as honouring .gitignore is git's job.
+//
+// It only works on local files, so it doesn't need the contexts it's given.
+//
+// Only SHA-1 repositories are supported.
+type Native struct {
+	objects *objectStore
+	dir     string // The directory the repo was opened from, paths are relative to this
+	prefix  string // dir relative to the root of the working tree, with forward slashes
+	root    string // The root of the working tree
+	gitDir  string // The .git directory, empty if dir isn't in a repo
+	common  string // Where refs and objects live, the same as gitDir except in linked worktrees
+}
+
+var _ Repo = (*Native)(nil)
+
+// NewNative returns a [Native] for the repository containing dir.
+//
+// If dir isn't in a git repository, IsRepo returns false and everything else
+// returns an error.
+func NewNative(dir string) *Native {
+	n := &Native{dir: dir}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return n
+	}
+
+	for current := abs; ; {
+		dotGit := filepath.Join(current, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			gitDir, err := resolveGitDir(dotGit, info)
+			if err == nil {
+				n.root = current
+				n.gitDir = gitDir
+			}
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return n
+		}
+		current = parent
+	}
+
+	if n.gitDir == "" {
+		return n
+	}
+
+	n.common = n.gitDir
+	if contents, err := os.ReadFile(filepath.Join(n.gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(contents))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(n.gitDir, common)
+		}
+		n.common = common
+	}
+
+	if rel, err := filepath.Rel(n.root, abs); err == nil && rel != "." {
+		n.prefix = filepath.ToSlash(rel)
+	}
+
+	n.objects = &objectStore{dir: filepath.Join(n.common, "objects")}
+	return n
+}
+
+// resolveGitDir returns the git directory a .git entry refers to, it's either
+// the directory itself or, for linked worktrees and submodules, a file pointing to it.
+func resolveGitDir(dotGit string, info fs.FileInfo) (string, error) {
+	if info.IsDir() {
+		return dotGit, nil
+	}
+
+	contents, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(contents)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("%s is not a gitdir file", dotGit)
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(dotGit), gitDir)
+	}
+	return gitDir, nil
+}
+
+// IsRepo reports whether the directory the repo was opened from is in a git repository.
+func (n *Native) IsRepo(context.Context) bool {
+	return n.gitDir != ""
+}
+
+// Branch returns the name of the current branch, or "HEAD" if detached.
+func (n *Native) Branch(context.Context) (string, error) {
+	if err := n.ensureRepo(); err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(filepath.Join(n.gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("could not read HEAD: %w", err)
+	}
+	if branch, ok := strings.CutPrefix(strings.TrimSpace(string(contents)), "ref: refs/heads/"); ok {
+		return branch, nil
+	}
+	return "HEAD", nil
+}
+
+// IsDirty is not supported, telling whether a file has changed means running it
+// through git's clean filters and line ending conversion first.
+func (n *Native) IsDirty(context.Context) (bool, error) {
+	_, err := unsupported("checking for changes")
+	return false, err
+}
+
+// ListFiles lists all the tracked files under the directory the repo was opened from.
+//
+// Unlike [ListFiles], untracked files are not included.
+func (n *Native) ListFiles(context.Context) ([]string, error) {
+	if err := n.ensureRepo(); err != nil {
+		return nil, err
+	}
+
+	paths, err := readIndex(filepath.Join(n.gitDir, "index"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range paths {
+		if rel, ok := n.relative(file); ok {
+			files = append(files, rel)
+		}
+	}
+
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// ListTags lists all the version tags in descending order (latest at the top), see [Exec.ListTags].
+func (n *Native) ListTags(_ context.Context, prefix string, limit int) (string, bool, error) {
+	names, err := n.tagNames(prefix)
+	if err != nil {
+		return "", false, err
+	}
+	if len(names) == 0 {
+		return "", false, ErrNoTagsFound
+	}
+
+	out := []byte(strings.Join(names, "\n") + "\n")
+	tags, limitHit := limitLines(out, limit)
+	return tags, limitHit, nil
+}
+
+// Tags returns the detail of all the version tags in descending order (latest first), see [Exec.Tags].
+func (n *Native) Tags(ctx context.Context, prefix string) ([]TagInfo, error) {
+	names, err := n.tagNames(prefix)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+
+	tags := make([]TagInfo, 0, len(names))
+	for _, name := range names {
+		tag, err := n.Tag(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// Tag returns the detail of a single tag.
+func (n *Native) Tag(_ context.Context, name string) (TagInfo, error) {
+	if err := n.ensureRepo(); err != nil {
+		return TagInfo{}, err
+	}
+
+	hash, err := n.readRef("refs/tags/" + name)
+	if err != nil {
+		return TagInfo{}, fmt.Errorf("tag %s not found", name)
+	}
+
+	typ, data, err := n.objects.read(hash)
+	if err != nil {
+		return TagInfo{}, err
+	}
+
+	info := TagInfo{Name: name}
+	if typ == typeTag {
+		tag, err := parseTag(data)
+		if err != nil {
+			return TagInfo{}, fmt.Errorf("bad tag %s: %w", name, err)
+		}
+		info.Date = tag.when
+		info.Message, _ = splitMessage(tag.message)
+	}
+
+	info.Commit, err = n.peel(hash)
+	if err != nil {
+		return TagInfo{}, err
+	}
+
+	if typ != typeTag {
+		// Lightweight, the date and message come from the commit
+		c, err := n.readCommit(info.Commit)
+		if err != nil {
+			return TagInfo{}, err
+		}
+		info.Date = c.when
+		info.Message, _ = splitMessage(c.message)
+	}
+
+	return info, nil
+}
+
+// LatestTag returns the name of the nearest version tag reachable from HEAD.
+//
+// Only tags of the form <prefix>v<version> are considered, as for [Exec.LatestTag].
+func (n *Native) LatestTag(ctx context.Context, prefix string) (string, error) {
+	names, err := n.tagNames(prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", ErrNoTagsFound
+	}
+
+	tagged := make(map[string][]TagInfo)
+	for _, name := range names {
+		tag, err := n.Tag(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		tagged[tag.Commit] = append(tagged[tag.Commit], tag)
+	}
+
+	head, err := n.resolveCommit("HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	var found []TagInfo
+	err = n.walk([]string{head}, nil, func(hash string, _ commit) bool {
+		found = tagged[hash]
+		return len(found) == 0
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(found) == 0 {
+		return "", fmt.Errorf("no tags can describe %s", head)
+	}
+
+	// Same as git describe, annotated tags win, then the newest
+	slices.SortStableFunc(found, func(a, b TagInfo) int {
+		aAnnotated, bAnnotated := n.isAnnotated(a.Name), n.isAnnotated(b.Name)
+		if aAnnotated != bAnnotated {
+			if aAnnotated {
+				return -1
+			}
+			return 1
+		}
+		return b.Date.Compare(a.Date)
+	})
+
+	return found[0].Name, nil
+}
+
+// TagMessage returns the message of an annotated tag, without any signature.
+func (n *Native) TagMessage(_ context.Context, tag string) (string, error) {
+	if err := n.ensureRepo(); err != nil {
+		return "", err
+	}
+
+	hash, err := n.readRef("refs/tags/" + tag)
+	if err != nil {
+		if errors.Is(err, errRefNotFound) {
+			// Same as git tag --list, nothing matches so there's no message
+			return "", nil
+		}
+		return "", fmt.Errorf("could not get message of tag %s: %w", tag, err)
+	}
+
+	typ, data, err := n.objects.read(hash)
+	if err != nil {
+		return "", err
+	}
+
+	var message string
+	switch typ {
+	case typeTag:
+		t, err := parseTag(data)
+		if err != nil {
+			return "", fmt.Errorf("bad tag %s: %w", tag, err)
+		}
+		message = t.message
+	case typeCommit:
+		c, err := parseCommit(data)
+		if err != nil {
+			return "", fmt.Errorf("bad commit %s: %w", hash, err)
+		}
+		message = c.message
+	}
+
+	subject, body := splitMessage(message)
+	if body != "" {
+		return subject + "\n\n" + body, nil
+	}
+	return subject, nil
+}
+
+// CreateTag is not supported.
+func (n *Native) CreateTag(context.Context, string, string) (string, error) {
+	return unsupported("creating tags")
+}
+
+// SignedTag is not supported, signing needs gpg or ssh-keygen which go through git.
+func (n *Native) SignedTag(context.Context, string, string, Signing) (string, error) {
+	return unsupported("signing tags")
+}
+
+// DeleteTag is not supported.
+func (n *Native) DeleteTag(context.Context, string) (string, error) {
+	return unsupported("deleting tags")
+}
+
+// VerifyTag is not supported, verifying needs gpg or ssh-keygen which go through git.
+func (n *Native) VerifyTag(context.Context, string) (Verification, error) {
+	_, err := unsupported("verifying tags")
+	return Verification{}, err
+}
+
+// RevParse returns the full hash of the commit ref points to.
+//
+// Refs may be full hashes, branch or tag names, or HEAD, followed by any
+// number of ~<n>, ^<n> or ^{commit} suffixes.
+func (n *Native) RevParse(_ context.Context, ref string) (string, error) {
+	hash, err := n.resolveCommit(ref)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve %s to a commit", ref)
+	}
+	return hash, nil
+}
+
+// CommitsSince returns all the commits reachable from HEAD but not from ref, newest first.
+//
+// If ref is empty, all commits reachable from HEAD are returned. If any paths are
+// given, only commits touching those paths are returned.
+func (n *Native) CommitsSince(_ context.Context, ref string, paths ...string) ([]LogEntry, error) {
+	head, err := n.resolveCommit("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := make(map[string]bool)
+	if ref != "" {
+		since, err := n.resolveCommit(ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not get commits since %q: %w", ref, err)
+		}
+		err = n.walk([]string{since}, nil, func(hash string, _ commit) bool {
+			exclude[hash] = true
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var pathspecs []string
+	for _, p := range paths {
+		pathspecs = append(pathspecs, n.repoPath(p))
+	}
+
+	var commits []LogEntry
+	var walkErr error
+	err = n.walk([]string{head}, exclude, func(hash string, c commit) bool {
+		if len(pathspecs) != 0 {
+			touched, err := n.touches(c, pathspecs)
+			if err != nil {
+				walkErr = err
+				return false
+			}
+			if !touched {
+				return true
+			}
+		}
+		commits = append(commits, LogEntry{Hash: hash, Message: strings.TrimSpace(c.message)})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	return commits, nil
+}
+
+// CommitMessage returns the full message of the commit ref points to.
+func (n *Native) CommitMessage(_ context.Context, ref string) (string, error) {
+	hash, err := n.resolveCommit(ref)
+	if err != nil {
+		return "", fmt.Errorf("could not get commit message of %s: %w", ref, err)
+	}
+	c, err := n.readCommit(hash)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(c.message), nil
+}
+
+// Show returns the contents of the file at path (relative to the directory the
+// repo was opened from) as of the commit ref points to.
+func (n *Native) Show(_ context.Context, ref, file string) ([]byte, error) {
+	hash, err := n.resolveCommit(ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not show %s at %s: %w", file, ref, err)
+	}
+	c, err := n.readCommit(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := n.lookup(c.tree, n.repoPath(file))
+	if err != nil {
+		return nil, err
+	}
+	if entry.hash == "" || entry.mode == "40000" {
+		return nil, fmt.Errorf("could not show %s at %s: not a file", file, ref)
+	}
+
+	return n.objects.readType(entry.hash, typeBlob)
+}
+
+// Add is not supported.
+func (n *Native) Add(context.Context, ...string) error {
+	_, err := unsupported("staging changes")
+	return err
+}
+
+// Commit is not supported.
+func (n *Native) Commit(context.Context, string) (string, error) {
+	return unsupported("committing")
+}
+
+// SignedCommit is not supported, signing needs gpg or ssh-keygen which go through git.
+func (n *Native) SignedCommit(context.Context, string, Signing) (string, error) {
+	return unsupported("signing commits")
+}
+
+// Reset is not supported.
+func (n *Native) Reset(context.Context, string) (string, error) {
+	return unsupported("resetting")
+}
+
+// ResetMixed is not supported.
+func (n *Native) ResetMixed(context.Context, string) (string, error) {
+	return unsupported("resetting")
+}
+
+// Push is not supported.
+func (n *Native) Push(context.Context, string, ...string) (string, error) {
+	return unsupported("pushing")
+}
+
+// DefaultRemote returns the remote a plain git push would use, that is the
+// remote of the current branch if it has one, otherwise "origin".
+//
+// If the repo has no remotes at all, an empty string is returned.
+func (n *Native) DefaultRemote(ctx context.Context) (string, error) {
+	if err := n.ensureRepo(); err != nil {
+		return "", err
+	}
+
+	cfg, err := readConfig(filepath.Join(n.common, "config"))
+	if err != nil {
+		return "", err
+	}
+
+	var remotes []string
+	for key := range cfg {
+		if rest, ok := strings.CutPrefix(key, "remote."); ok {
+			if i := strings.LastIndex(rest, "."); i > 0 {
+				remotes = append(remotes, rest[:i])
+			}
+		}
+	}
+	if len(remotes) == 0 {
+		return "", nil
+	}
+	slices.Sort(remotes)
+
+	if branch, err := n.Branch(ctx); err == nil {
+		if remote := cfg["branch."+branch+".remote"]; remote != "" {
+			return remote, nil
+		}
+	}
+
+	if slices.Contains(remotes, "origin") {
+		return "origin", nil
+	}
+	return remotes[0], nil
+}
+
+// RemoteHasTag is not supported.
+func (n *Native) RemoteHasTag(context.Context, string, string) (bool, error) {
+	_, err := unsupported("talking to remotes")
+	return false, err
+}
+
+// DeleteRemoteTag is not supported.
+func (n *Native) DeleteRemoteTag(context.Context, string, string) (string, error) {
+	return unsupported("talking to remotes")
+}
+
+// unsupported is a helper that returns the error for an unsupported operation, along
+// with its message as the output as the callers of methods returning output use that
+// as the error.
+func unsupported(operation string) (string, error) {
+	err := fmt.Errorf("%s is %w", operation, ErrUnsupported)
+	return err.Error(), err
+}
+
+// ensureRepo is a helper that errors if the repo doesn't exist.
+func (n *Native) ensureRepo() error {
+	if n.gitDir == "" {
+		return errors.New("not a git repository")
+	}
+	return nil
+}
+
+// repoPath converts a path relative to the directory the repo was opened from into
+// a path from the root of the working tree, with forward slashes.
+func (n *Native) repoPath(file string) string {
+	p := path.Join(n.prefix, filepath.ToSlash(file))
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// relative converts a path from the root of the working tree into one relative to the
+// directory the repo was opened from, reporting false if it isn't under it.
+func (n *Native) relative(file string) (string, bool) {
+	if n.prefix == "" {
+		return file, true
+	}
+	return strings.CutPrefix(file, n.prefix+"/")
+}
+
+// readRef returns the hash a ref points to, following symbolic refs.
+func (n *Native) readRef(name string) (string, error) {
+	for range maxSymrefs {
+		dir := n.common
+		if name == "HEAD" {
+			dir = n.gitDir
+		}
+
+		contents, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			// Not a loose ref, which includes it being a directory e.g. refs/tags/api
+			packed, err := n.packedRefs()
+			if err != nil {
+				return "", err
+			}
+			if hash, ok := packed[name]; ok {
+				return hash, nil
+			}
+			return "", fmt.Errorf("%w: %s", errRefNotFound, name)
+		}
+
+		value := strings.TrimSpace(string(contents))
+		if target, ok := strings.CutPrefix(value, "ref: "); ok {
+			name = target
+			continue
+		}
+		if !hexHash.MatchString(value) {
+			return "", fmt.Errorf("bad ref %s: %q", name, value)
+		}
+		return value, nil
+	}
+
+	return "", fmt.Errorf("too many levels of symbolic refs resolving %s", name)
+}
+
+// packedRefs reads the packed-refs file, returning a map of ref name to hash.
+func (n *Native) packedRefs() (map[string]string, error) {
+	refs := make(map[string]string)
+
+	f, err := os.Open(filepath.Join(n.common, "packed-refs"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return refs, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			// Header, or the peeled value of the tag above
+			continue
+		}
+		hash, name, ok := strings.Cut(line, " ")
+		if ok && hexHash.MatchString(hash) {
+			refs[name] = hash
+		}
+	}
+
+	return refs, scanner.Err()
+}
+
+// tagNames returns the names of the tags of the form <prefix>v<version>, in
+// descending version order.
+func (n *Native) tagNames(prefix string) ([]string, error) {
+	if err := n.ensureRepo(); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+
+	packed, err := n.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+	for ref := range packed {
+		if name, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+			names[name] = true
+		}
+	}
+
+	tagDir := filepath.Join(n.common, "refs", "tags")
+	err = filepath.WalkDir(tagDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(tagDir, p)
+		if err != nil {
+			return err
+		}
+		names[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list tags: %w", err)
+	}
+
+	pattern := tagPattern(prefix)
+
+	var matched []string
+	for name := range names {
+		if ok, _ := path.Match(pattern, name); ok { //nolint: errcheck // The pattern is always valid
+			matched = append(matched, name)
+		}
+	}
+
+	slices.SortFunc(matched, func(a, b string) int {
+		return versionCompare(b, a) // Descending
+	})
+
+	return matched, nil
+}
+
+// isAnnotated reports whether the tag with the given name is an annotated tag.
+func (n *Native) isAnnotated(name string) bool {
+	hash, err := n.readRef("refs/tags/" + name)
+	if err != nil {
+		return false
+	}
+	typ, _, err := n.objects.read(hash)
+	return err == nil && typ == typeTag
+}
+
+// resolve returns the hash of the object rev refers to, see RevParse for what's supported.
+func (n *Native) resolve(rev string) (string, error) {
+	if err := n.ensureRepo(); err != nil {
+		return "", err
+	}
+
+	base, suffixes := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i >= 0 {
+		base, suffixes = rev[:i], rev[i:]
+	}
+
+	hash, err := n.resolveBase(base)
+	if err != nil {
+		return "", err
+	}
+
+	for suffixes != "" {
+		switch {
+		case strings.HasPrefix(suffixes, "^{commit}"), strings.HasPrefix(suffixes, "^{}"):
+			_, rest, _ := strings.Cut(suffixes, "}")
+			suffixes = rest
+			if hash, err = n.peel(hash); err != nil {
+				return "", err
+			}
+		case suffixes[0] == '~', suffixes[0] == '^':
+			op := suffixes[0]
+			digits := strings.IndexFunc(suffixes[1:], func(r rune) bool { return r < '0' || r > '9' })
+			if digits < 0 {
+				digits = len(suffixes) - 1
+			}
+			count := 1
+			if digits > 0 {
+				if count, err = strconv.Atoi(suffixes[1 : 1+digits]); err != nil {
+					return "", fmt.Errorf("bad revision %s", rev)
+				}
+			}
+			suffixes = suffixes[1+digits:]
+
+			if hash, err = n.peel(hash); err != nil {
+				return "", err
+			}
+			if hash, err = n.ancestor(hash, op, count); err != nil {
+				return "", fmt.Errorf("bad revision %s: %w", rev, err)
+			}
+		default:
+			return "", fmt.Errorf("bad revision %s", rev)
+		}
+	}
+
+	return hash, nil
+}
+
+// resolveBase resolves a hash or ref name, trying the same places git does.
+func (n *Native) resolveBase(name string) (string, error) {
+	if hexHash.MatchString(name) {
+		if _, _, err := n.objects.read(name); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+
+	candidates := []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name, "refs/remotes/" + name, "refs/remotes/" + name + "/HEAD"}
+	for _, candidate := range candidates {
+		if candidate != "HEAD" && !strings.HasPrefix(candidate, "refs/") {
+			continue
+		}
+		hash, err := n.readRef(candidate)
+		if err == nil {
+			return hash, nil
+		}
+		if !errors.Is(err, errRefNotFound) {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", errRefNotFound, name)
+}
+
+// ancestor follows ~count (first parents) or ^count (the count'th parent) from the commit hash.
+func (n *Native) ancestor(hash string, op byte, count int) (string, error) {
+	if op == '^' {
+		if count == 0 {
+			return hash, nil
+		}
+		c, err := n.readCommit(hash)
+		if err != nil {
+			return "", err
+		}
+		if count > len(c.parents) {
+			return "", fmt.Errorf("commit %.7s has no parent %d", hash, count)
+		}
+		return c.parents[count-1], nil
+	}
+
+	for range count {
+		c, err := n.readCommit(hash)
+		if err != nil {
+			return "", err
+		}
+		if len(c.parents) == 0 {
+			return "", fmt.Errorf("commit %.7s has no parent", hash)
+		}
+		hash = c.parents[0]
+	}
+	return hash, nil
+}
+
+// resolveCommit resolves rev and peels it to a commit.
+func (n *Native) resolveCommit(rev string) (string, error) {
+	hash, err := n.resolve(rev)
+	if err != nil {
+		return "", err
+	}
+	return n.peel(hash)
+}
+
+// peel follows tags until it gets to a commit.
+func (n *Native) peel(hash string) (string, error) {
+	for range maxSymrefs {
+		typ, data, err := n.objects.read(hash)
+		if err != nil {
+			return "", err
+		}
+		switch typ {
+		case typeCommit:
+			return hash, nil
+		case typeTag:
+			tag, err := parseTag(data)
+			if err != nil {
+				return "", fmt.Errorf("bad tag %s: %w", hash, err)
+			}
+			hash = tag.object
+		default:
+			return "", fmt.Errorf("object %s is a %s, not a commit", hash, typ)
+		}
+	}
+	return "", fmt.Errorf("too many nested tags at %s", hash)
+}
+
+// readCommit reads and parses a commit.
+func (n *Native) readCommit(hash string) (commit, error) {
+	data, err := n.objects.readType(hash, typeCommit)
+	if err != nil {
+		return commit{}, err
+	}
+	c, err := parseCommit(data)
+	if err != nil {
+		return commit{}, fmt.Errorf("bad commit %s: %w", hash, err)
+	}
+	return c, nil
+}
+
+// walk visits the commits reachable from start newest first (by commit date), the same
+// order as git log, skipping any in exclude. It stops when visit returns false.
+func (n *Native) walk(start []string, exclude map[string]bool, visit func(hash string, c commit) bool) error {
+	queue := &commitQueue{}
+	seen := make(map[string]bool)
+
+	push := func(hash string) error {
+		if seen[hash] || exclude[hash] {
+			return nil
+		}
+		seen[hash] = true
+		c, err := n.readCommit(hash)
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, queued{hash: hash, commit: c})
+		return nil
+	}
+
+	for _, hash := range start {
+		if err := push(hash); err != nil {
+			return err
+		}
+	}
+
+	for queue.Len() != 0 {
+		next := heap.Pop(queue).(queued) //nolint: errcheck,forcetypeassert // Only queued go in
+		if !visit(next.hash, next.commit) {
+			return nil
+		}
+		for _, parent := range next.commit.parents {
+			if err := push(parent); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// touches reports whether the commit changed anything under any of the paths, that is it
+// isn't the same as any of its parents there. A root commit touches any path that exists.
+func (n *Native) touches(c commit, paths []string) (bool, error) {
+	if len(c.parents) == 0 {
+		for _, p := range paths {
+			entry, err := n.lookup(c.tree, p)
+			if err != nil {
+				return false, err
+			}
+			if entry.hash != "" {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	for _, parent := range c.parents {
+		pc, err := n.readCommit(parent)
+		if err != nil {
+			return false, err
+		}
+		same := true
+		for _, p := range paths {
+			before, err := n.lookup(pc.tree, p)
+			if err != nil {
+				return false, err
+			}
+			after, err := n.lookup(c.tree, p)
+			if err != nil {
+				return false, err
+			}
+			if before != after {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// lookup finds the entry at path (from the root of the working tree) in a tree, the
+// entry has an empty hash if there's nothing there. An empty path is the tree itself.
+func (n *Native) lookup(tree, file string) (treeEntry, error) {
+	entry := treeEntry{hash: tree, mode: "40000"}
+	if file == "" {
+		return entry, nil
+	}
+
+	for part := range strings.SplitSeq(file, "/") {
+		if entry.mode != "40000" {
+			return treeEntry{}, nil
+		}
+		data, err := n.objects.readType(entry.hash, typeTree)
+		if err != nil {
+			return treeEntry{}, err
+		}
+		entries, err := parseTree(data)
+		if err != nil {
+			return treeEntry{}, err
+		}
+		i := slices.IndexFunc(entries, func(e treeEntry) bool { return e.name == part })
+		if i < 0 {
+			return treeEntry{}, nil
+		}
+		entry = entries[i]
+	}
+
+	return entry, nil
+}
+
+// flattenTree adds every file in the tree to files, keyed by path from the root of the working tree.
+func (n *Native) flattenTree(tree, dir string, files map[string]treeEntry) error {
+	data, err := n.objects.readType(tree, typeTree)
+	if err != nil {
+		return err
+	}
+	entries, err := parseTree(data)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		p := path.Join(dir, entry.name)
+		if entry.mode == "40000" {
+			if err := n.flattenTree(entry.hash, p, files); err != nil {
+				return err
+			}
+			continue
+		}
+		files[p] = entry
+	}
+
+	return nil
+}
+
+// readConfig is a minimal git config reader, returning a map of "section.subsection.key"
+// to value with later values winning. A missing file is empty, includes are not followed.
+func readConfig(file string) (map[string]string, error) {
+	cfg := make(map[string]string)
+
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	section := ""
+	for line := range strings.Lines(string(contents)) {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.LastIndex(line, "]")
+			if end < 0 {
+				continue
+			}
+			name, sub, ok := strings.Cut(line[1:end], " ")
+			section = strings.ToLower(name)
+			if ok {
+				// Subsections are case sensitive
+				section += "." + strings.Trim(strings.TrimSpace(sub), `"`)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			value = "true"
+		}
+		value = strings.TrimSpace(value)
+		if i := strings.IndexAny(value, "#;"); i >= 0 && !strings.HasPrefix(value, `"`) {
+			value = strings.TrimSpace(value[:i])
+		}
+		cfg[section+"."+strings.ToLower(strings.TrimSpace(key))] = strings.Trim(value, `"`)
+	}
+
+	return cfg, nil
+}
+
+// splitMessage splits a commit or tag message into its subject (the first paragraph,
+// on one line) and body, dropping any signature.
+func splitMessage(message string) (subject, body string) {
+	if loc := signatureStart.FindStringIndex(message); loc != nil {
+		message = message[:loc[0]]
+	}
+	message = strings.TrimSpace(message)
+
+	first, body, _ := strings.Cut(message, "\n\n")
+	lines := strings.Split(first, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	return strings.Join(lines, " "), strings.TrimSpace(body)
+}
+
+// versionCompare compares two tag names the way git's version sort does, runs
+// of digits are compared as numbers so "v1.10.0" sorts after "v1.9.0".
+func versionCompare(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			aEnd := digitsEnd(a)
+			bEnd := digitsEnd(b)
+			aNum := strings.TrimLeft(a[:aEnd], "0")
+			bNum := strings.TrimLeft(b[:bEnd], "0")
+			if c := cmp.Compare(len(aNum), len(bNum)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(aNum, bNum); c != 0 {
+				return c
+			}
+			a, b = a[aEnd:], b[bEnd:]
+			continue
+		}
+		if c := cmp.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// isDigit reports whether b is an ASCII digit.
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// digitsEnd returns the index of the end of the run of digits at the start of s.
+func digitsEnd(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// queued is a commit waiting to be visited in a walk.
+type queued struct {
+	hash   string
+	commit commit
+}
+
+// commitQueue is a priority queue of commits, newest first.
+type commitQueue []queued
+
+func (q commitQueue) Len() int           { return len(q) }
+func (q commitQueue) Less(i, j int) bool { return q[i].commit.when.After(q[j].commit.when) }
+func (q commitQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)        { *q = append(*q, x.(queued)) } //nolint: forcetypeassert // Only queued go in
+
+func (q *commitQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,540 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Object types, as named in git's object headers.
+const (
+	typeCommit = "commit"
+	typeTree   = "tree"
+	typeBlob   = "blob"
+	typeTag    = "tag"
+)
+
+// Pack object types, as stored in the pack object header.
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+const (
+	hashSize   = 20         // Size of a SHA-1 hash in bytes
+	idxVersion = 2          // The only pack index version we read
+	idxMagic   = "\xfftOc"  // Pack index v2 magic
+	maxDeltas  = 50         // Way deeper than git ever makes, stops a corrupt pack looping
+	large      = 0x80000000 // Pack index offsets with this bit set are in the 64 bit table
+)
+
+// errObjectNotFound is returned when an object isn't in the store.
+var errObjectNotFound = errors.New("object not found")
+
+// objectStore reads and writes git objects, loose or packed.
+type objectStore struct {
+	dir   string  // Path to the objects directory
+	packs []*pack // Loaded lazily, nil until first needed
+}
+
+// pack is a packfile and its index.
+type pack struct {
+	path    string   // Path to the .pack file
+	fanout  []uint32 // Cumulative count of objects by first hash byte
+	names   []byte   // Sorted object hashes, hashSize bytes each
+	offsets []byte   // 4 byte offsets into the pack, one per object
+	large   []byte   // 8 byte offsets for packs over 2GB
+}
+
+// read returns the type and contents of the object with the given hex hash.
+func (s *objectStore) read(hash string) (string, []byte, error) {
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != hashSize {
+		return "", nil, fmt.Errorf("bad object name %q", hash)
+	}
+
+	typ, data, err := s.readLoose(hash)
+	if err == nil {
+		return typ, data, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", nil, err
+	}
+
+	if err := s.loadPacks(); err != nil {
+		return "", nil, err
+	}
+
+	for _, p := range s.packs {
+		offset, ok := p.find(raw)
+		if !ok {
+			continue
+		}
+		typ, data, err := s.readPacked(p, offset)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not read object %s from %s: %w", hash, filepath.Base(p.path), err)
+		}
+		return typ, data, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: %s", errObjectNotFound, hash)
+}
+
+// readType is a helper that reads an object and checks it's the expected type.
+func (s *objectStore) readType(hash, want string) ([]byte, error) {
+	typ, data, err := s.read(hash)
+	if err != nil {
+		return nil, err
+	}
+	if typ != want {
+		return nil, fmt.Errorf("object %s is a %s, not a %s", hash, typ, want)
+	}
+	return data, nil
+}
+
+// readLoose reads a loose object.
+func (s *objectStore) readLoose(hash string) (string, []byte, error) {
+	f, err := os.Open(filepath.Join(s.dir, hash[:2], hash[2:]))
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	z, err := zlib.NewReader(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not decompress object %s: %w", hash, err)
+	}
+	defer z.Close()
+
+	contents, err := io.ReadAll(z)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not decompress object %s: %w", hash, err)
+	}
+
+	header, data, ok := bytes.Cut(contents, []byte{0})
+	if !ok {
+		return "", nil, fmt.Errorf("object %s has no header", hash)
+	}
+	typ, size, ok := strings.Cut(string(header), " ")
+	if !ok || size != strconv.Itoa(len(data)) {
+		return "", nil, fmt.Errorf("object %s has a bad header %q", hash, header)
+	}
+
+	return typ, data, nil
+}
+
+// loadPacks reads the index of every pack in the store, if it hasn't already.
+func (s *objectStore) loadPacks() error {
+	if s.packs != nil {
+		return nil
+	}
+
+	indexes, err := filepath.Glob(filepath.Join(s.dir, "pack", "*.idx"))
+	if err != nil {
+		return err
+	}
+
+	s.packs = make([]*pack, 0, len(indexes))
+	for _, index := range indexes {
+		p, err := loadPack(index)
+		if err != nil {
+			return err
+		}
+		s.packs = append(s.packs, p)
+	}
+
+	return nil
+}
+
+// loadPack reads the pack index at path.
+func loadPack(path string) (*pack, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	const header = 8 + 256*4 // magic, version, fanout table
+	if len(contents) < header || string(contents[:4]) != idxMagic || binary.BigEndian.Uint32(contents[4:8]) != idxVersion {
+		return nil, fmt.Errorf("unsupported pack index %s, only version %d is supported", filepath.Base(path), idxVersion)
+	}
+
+	fanout := make([]uint32, 256) //nolint: mnd // One per possible first byte
+	for i := range fanout {
+		fanout[i] = binary.BigEndian.Uint32(contents[8+i*4:])
+	}
+
+	count := int(fanout[255])
+	namesStart := header
+	offsetsStart := namesStart + count*hashSize + count*4 // Skip the CRCs
+	largeStart := offsetsStart + count*4
+	if len(contents) < largeStart {
+		return nil, fmt.Errorf("truncated pack index %s", filepath.Base(path))
+	}
+
+	return &pack{
+		path:    strings.TrimSuffix(path, ".idx") + ".pack",
+		fanout:  fanout,
+		names:   contents[namesStart : namesStart+count*hashSize],
+		offsets: contents[offsetsStart:largeStart],
+		large:   contents[largeStart:],
+	}, nil
+}
+
+// find returns the offset of the object with the given hash in the pack.
+func (p *pack) find(hash []byte) (int64, bool) {
+	lo := 0
+	if hash[0] > 0 {
+		lo = int(p.fanout[hash[0]-1])
+	}
+	hi := int(p.fanout[hash[0]])
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2 //nolint: mnd // Halfway
+		switch bytes.Compare(p.names[mid*hashSize:(mid+1)*hashSize], hash) {
+		case 0:
+			return p.offset(mid), true
+		case -1:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return 0, false
+}
+
+// offset returns the offset in the pack of the i'th object in the index.
+func (p *pack) offset(i int) int64 {
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&large == 0 {
+		return int64(offset)
+	}
+	i = int(offset &^ large)
+	return int64(binary.BigEndian.Uint64(p.large[i*8:])) //nolint: gosec // Packs are never that big
+}
+
+// readPacked reads the object at offset in the pack, resolving any deltas.
+func (s *objectStore) readPacked(p *pack, offset int64) (string, []byte, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	return s.readPackedFrom(f, offset, 0)
+}
+
+// readPackedFrom reads the object at offset in the open pack f, depth is the
+// number of deltas followed to get here.
+func (s *objectStore) readPackedFrom(f *os.File, offset int64, depth int) (string, []byte, error) {
+	if depth > maxDeltas {
+		return "", nil, errors.New("delta chain too long")
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62)) //nolint: mnd // Read to the end, zlib knows where to stop
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	kind := (b >> 4) & 0x7 //nolint: mnd // Type is bits 4-6
+	for b&0x80 != 0 {
+		// The rest of the size, which we don't need as zlib knows where to stop
+		if b, err = r.ReadByte(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var base struct {
+		typ  string
+		data []byte
+	}
+
+	switch kind {
+	case packOfsDelta:
+		distance, err := readOffset(r)
+		if err != nil {
+			return "", nil, err
+		}
+		base.typ, base.data, err = s.readPackedFrom(f, offset-distance, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+	case packRefDelta:
+		hash := make([]byte, hashSize)
+		if _, err := io.ReadFull(r, hash); err != nil {
+			return "", nil, err
+		}
+		base.typ, base.data, err = s.read(hex.EncodeToString(hash))
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	z, err := zlib.NewReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	defer z.Close()
+
+	data, err := io.ReadAll(z)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch kind {
+	case packCommit:
+		return typeCommit, data, nil
+	case packTree:
+		return typeTree, data, nil
+	case packBlob:
+		return typeBlob, data, nil
+	case packTag:
+		return typeTag, data, nil
+	case packOfsDelta, packRefDelta:
+		patched, err := applyDelta(base.data, data)
+		return base.typ, patched, err
+	default:
+		return "", nil, fmt.Errorf("unknown pack object type %d", kind)
+	}
+}
+
+// readOffset reads the variable length negative offset of an ofs-delta base.
+func readOffset(r io.ByteReader) (int64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	offset := int64(b & 0x7f) //nolint: mnd // Low 7 bits
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		offset = ((offset + 1) << 7) | int64(b&0x7f) //nolint: mnd // Each byte adds 7 bits
+	}
+	return offset, nil
+}
+
+// applyDelta applies a git delta to base.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+
+	baseSize, err := binary.ReadUvarint(r)
+	if err != nil || baseSize != uint64(len(base)) {
+		return nil, errors.New("delta does not match its base")
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.New("bad delta size")
+	}
+
+	out := make([]byte, 0, size)
+	for {
+		op, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		switch {
+		case op&0x80 != 0:
+			// Copy from base, the low 4 bits say which offset bytes follow, the next 3 the size bytes
+			var offset, length uint64
+			for i := range 4 {
+				if op&(1<<i) != 0 {
+					b, _ := r.ReadByte() //nolint: errcheck // Caught by the bounds check below
+					offset |= uint64(b) << (8 * i)
+				}
+			}
+			for i := range 3 {
+				if op&(1<<(4+i)) != 0 {
+					b, _ := r.ReadByte() //nolint: errcheck // Caught by the bounds check below
+					length |= uint64(b) << (8 * i)
+				}
+			}
+			if length == 0 {
+				length = 0x10000
+			}
+			if offset+length > uint64(len(base)) {
+				return nil, errors.New("delta copies past the end of its base")
+			}
+			out = append(out, base[offset:offset+length]...)
+		case op != 0:
+			// Insert the next op bytes
+			start := len(delta) - r.Len()
+			if start+int(op) > len(delta) {
+				return nil, errors.New("delta inserts past its end")
+			}
+			out = append(out, delta[start:start+int(op)]...)
+			if _, err := r.Seek(int64(op), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New("bad delta opcode 0")
+		}
+	}
+
+	if uint64(len(out)) != size {
+		return nil, errors.New("delta produced the wrong size")
+	}
+
+	return out, nil
+}
+
+// commit is a parsed commit object.
+type commit struct {
+	when    time.Time
+	tree    string
+	message string
+	parents []string
+}
+
+// parseCommit parses the contents of a commit object.
+func parseCommit(data []byte) (commit, error) {
+	headers, message := parseHeaders(data)
+
+	var c commit
+	for _, header := range headers {
+		switch header.key {
+		case "tree":
+			c.tree = header.value
+		case "parent":
+			c.parents = append(c.parents, header.value)
+		case "committer":
+			c.when = parseSignatureTime(header.value)
+		}
+	}
+
+	if c.tree == "" {
+		return commit{}, errors.New("commit has no tree")
+	}
+	c.message = message
+	return c, nil
+}
+
+// tagObject is a parsed annotated tag object.
+type tagObject struct {
+	when    time.Time
+	object  string
+	typ     string
+	message string
+}
+
+// parseTag parses the contents of a tag object.
+func parseTag(data []byte) (tagObject, error) {
+	headers, message := parseHeaders(data)
+
+	var t tagObject
+	for _, header := range headers {
+		switch header.key {
+		case "object":
+			t.object = header.value
+		case "type":
+			t.typ = header.value
+		case "tagger":
+			t.when = parseSignatureTime(header.value)
+		}
+	}
+
+	if t.object == "" {
+		return tagObject{}, errors.New("tag has no object")
+	}
+	t.message = message
+	return t, nil
+}
+
+// header is a single header of a commit or tag object.
+type header struct {
+	key   string
+	value string
+}
+
+// parseHeaders splits a commit or tag object into its headers and message.
+func parseHeaders(data []byte) ([]header, string) {
+	var headers []header
+	rest := string(data)
+	for rest != "" {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, " ") && len(headers) != 0 {
+			// Continuation of a multi line header e.g. gpgsig
+			headers[len(headers)-1].value += "\n" + line[1:]
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		headers = append(headers, header{key: key, value: value})
+	}
+	return headers, rest
+}
+
+// parseSignatureTime gets the time from an author, committer or tagger header
+// e.g. "Tag Test <tag@test.com> 1700000000 +0100".
+func parseSignatureTime(signature string) time.Time {
+	_, when, ok := strings.Cut(signature, "> ")
+	if !ok {
+		return time.Time{}
+	}
+	seconds, zone, _ := strings.Cut(when, " ")
+	unix, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	t := time.Unix(unix, 0)
+	if len(zone) != 5 { //nolint: mnd // e.g. +0100
+		return t
+	}
+	hours, err1 := strconv.Atoi(zone[1:3])
+	minutes, err2 := strconv.Atoi(zone[3:])
+	if err1 != nil || err2 != nil {
+		return t
+	}
+	offset := hours*3600 + minutes*60
+	if zone[0] == '-' {
+		offset = -offset
+	}
+	return t.In(time.FixedZone("", offset))
+}
+
+// treeEntry is a single entry in a tree object.
+type treeEntry struct {
+	name string
+	mode string
+	hash string
+}
+
+// parseTree parses the contents of a tree object.
+func parseTree(data []byte) ([]treeEntry, error) {
+	var entries []treeEntry
+	for len(data) != 0 {
+		space := bytes.IndexByte(data, ' ')
+		null := bytes.IndexByte(data, 0)
+		if space < 0 || null < space || len(data) < null+1+hashSize {
+			return nil, errors.New("malformed tree object")
+		}
+		entries = append(entries, treeEntry{
+			mode: string(data[:space]),
+			name: string(data[space+1 : null]),
+			hash: hex.EncodeToString(data[null+1 : null+1+hashSize]),
+		})
+		data = data[null+1+hashSize:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// ErrUnsupported is returned by a [Repo] for any operation it can't do.
+var ErrUnsupported = errors.New("not supported without the git binary")
+
+// Repo is a git repository, everything tag does with git goes through one.
+//
+// There are two implementations: [Exec], which shells out to the git binary and
+// can do everything, and [Native], which works in pure Go for when git isn't
+// installed but can only read. [Open] picks the right one.
+type Repo interface {
+	// IsRepo reports whether the repo actually exists.
+	IsRepo(ctx context.Context) bool
+
+	// Branch returns the name of the current branch, or "HEAD" if detached.
+	Branch(ctx context.Context) (string, error)
+
+	// IsDirty reports whether there are any uncommitted changes.
+	IsDirty(ctx context.Context) (bool, error)
+
+	// ListFiles lists the files under the repo's directory that git knows about,
+	// see [Exec.ListFiles].
+	ListFiles(ctx context.Context) ([]string, error)
+
+	// ListTags lists version tags in descending order, see [Exec.ListTags].
+	ListTags(ctx context.Context, prefix string, limit int) (tags string, limitHit bool, err error)
+
+	// Tags returns the detail of all version tags in descending order, see [Exec.Tags].
+	Tags(ctx context.Context, prefix string) ([]TagInfo, error)
+
+	// Tag returns the detail of a single tag.
+	Tag(ctx context.Context, name string) (TagInfo, error)
+
+	// LatestTag returns the name of the latest version tag reachable from HEAD.
+	LatestTag(ctx context.Context, prefix string) (string, error)
+
+	// TagMessage returns the message of an annotated tag, without any signature.
+	TagMessage(ctx context.Context, tag string) (string, error)
+
+	// CreateTag creates an annotated tag on HEAD.
+	CreateTag(ctx context.Context, tag, message string) (string, error)
+
+	// SignedTag creates a signed, annotated tag on HEAD.
+	SignedTag(ctx context.Context, tag, message string, signing Signing) (string, error)
+
+	// DeleteTag deletes a local tag.
+	DeleteTag(ctx context.Context, tag string) (string, error)
+
+	// VerifyTag checks the signature on a tag.
+	VerifyTag(ctx context.Context, tag string) (Verification, error)
+
+	// RevParse returns the full hash of the commit ref points to.
+	RevParse(ctx context.Context, ref string) (string, error)
+
+	// CommitsSince returns the commits reachable from HEAD but not ref, see [Exec.CommitsSince].
+	CommitsSince(ctx context.Context, ref string, paths ...string) ([]LogEntry, error)
+
+	// CommitMessage returns the full message of the commit ref points to.
+	CommitMessage(ctx context.Context, ref string) (string, error)
+
+	// Show returns the contents of a file as of the commit ref points to.
+	Show(ctx context.Context, ref, path string) ([]byte, error)
+
+	// Add stages paths (relative to the repo's directory), which may be new files,
+	// see [Exec.Add].
+	Add(ctx context.Context, paths ...string) error
+
+	// Commit commits the staged changes.
+	Commit(ctx context.Context, message string) (string, error)
+
+	// SignedCommit commits the staged changes, signing the commit.
+	SignedCommit(ctx context.Context, message string, signing Signing) (string, error)
+
+	// Reset moves the current branch back to ref, see [Exec.Reset].
+	Reset(ctx context.Context, ref string) (string, error)
+
+	// ResetMixed moves the current branch back to ref, leaving the working tree alone.
+	ResetMixed(ctx context.Context, ref string) (string, error)
+
+	// Push pushes refs to remote atomically, see [Exec.Push].
+	Push(ctx context.Context, remote string, refs ...string) (string, error)
+
+	// DefaultRemote returns the remote a plain git push would use, see [Exec.DefaultRemote].
+	DefaultRemote(ctx context.Context) (string, error)
+
+	// RemoteHasTag reports whether the remote has the given tag.
+	RemoteHasTag(ctx context.Context, remote, tag string) (bool, error)
+
+	// DeleteRemoteTag deletes a tag from the remote.
+	DeleteRemoteTag(ctx context.Context, remote, tag string) (string, error)
+}
+
+// Open returns the [Repo] for the repository containing dir.
+//
+// If the git binary is installed, it's an [Exec] running in dir, otherwise it's a [Native].
+func Open(dir string) Repo {
+	if _, err := exec.LookPath("git"); err == nil {
+		return Exec{Dir: dir}
+	}
+	return NewNative(dir)
+}
+
+// Exec is a [Repo] that shells out to the git binary.
+type Exec struct {
+	Dir string // The directory to run git in, empty means the current directory
+
+	// Makes the git commands, nil means [exec.CommandContext], it's only set by tests
+	commandContext func(ctx context.Context, name string, args ...string) *exec.Cmd
+}
+
+var _ Repo = Exec{}
+
+// command returns the git command with args, to be run in the repo's directory.
+func (e Exec) command(ctx context.Context, args ...string) *exec.Cmd {
+	commandContext := e.commandContext
+	if commandContext == nil {
+		commandContext = exec.CommandContext
+	}
+	cmd := commandContext(ctx, "git", args...)
+	cmd.Dir = e.Dir
+	return cmd
+}
@@ -0,0 +1,195 @@
+// Package gomod implements moving a Go module to a new major version.
+//
+// Go's semantic import versioning means a module at v2 or above must have a
+// major version suffix on its module path e.g. "example.com/lib/v2", so every
+// major bump past v1 has to rewrite the module directive in go.mod as well as
+// every import of the module's own packages.
+//
+// Import paths in .go files are rewritten using go/parser and go/printer so
+// the result is exactly what gofmt would produce.
+package gomod
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filename is the name of the Go module file.
+const Filename = "go.mod"
+
+var (
+	// majorSuffix matches a valid major version suffix on a module path, v0 and v1
+	// never have one and the version must not have leading zeros.
+	majorSuffix = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)$`)
+
+	// moduleDirective matches the module line in a go.mod file, capturing the path.
+	moduleDirective = regexp.MustCompile(`(?m)^module[ \t]+"?([^\s"]+)"?`)
+)
+
+// Plan is the set of changes needed to move a module to a new major version.
+type Plan struct {
+	From    string   // The current module path
+	To      string   // The module path for the new major version
+	Changes []Change // The files that need rewriting, empty if From == To
+}
+
+// Change is a single file that needs rewriting.
+type Change struct {
+	Path     string // Path to the file
+	Contents []byte // The rewritten contents
+	Count    int    // Number of module paths rewritten in the file
+}
+
+// ModulePath returns the path the module currently at path should have for the
+// given major version, adding, changing or removing the major version suffix.
+func ModulePath(path string, major uint64) (string, error) {
+	if strings.HasPrefix(path, "gopkg.in/") {
+		return "", fmt.Errorf("gopkg.in module paths are not supported: %s", path)
+	}
+
+	base := path
+	if loc := majorSuffix.FindStringIndex(path); loc != nil {
+		base = path[:loc[0]]
+	}
+
+	if major < 2 { //nolint: mnd // v0 and v1 have no suffix
+		return base, nil
+	}
+
+	return fmt.Sprintf("%s/v%d", base, major), nil
+}
+
+// Rewrite works out the changes needed to move the module rooted at dir to the
+// given major version, nothing is written to disk.
+//
+// Nested modules (directories with their own go.mod), vendor and testdata
+// directories and anything go would ignore (names starting with "." or "_")
+// are skipped.
+func Rewrite(dir string, major uint64) (Plan, error) {
+	modFile := filepath.Join(dir, Filename)
+	contents, err := os.ReadFile(modFile)
+	if err != nil {
+		return Plan{}, fmt.Errorf("could not read %s: %w", modFile, err)
+	}
+
+	loc := moduleDirective.FindSubmatchIndex(contents)
+	if loc == nil {
+		return Plan{}, fmt.Errorf("no module directive found in %s", modFile)
+	}
+
+	from := string(contents[loc[2]:loc[3]])
+	to, err := ModulePath(from, major)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{From: from, To: to}
+	if from == to {
+		return plan, nil
+	}
+
+	var updated []byte
+	updated = append(updated, contents[:loc[2]]...)
+	updated = append(updated, to...)
+	updated = append(updated, contents[loc[3]:]...)
+	plan.Changes = append(plan.Changes, Change{Path: modFile, Contents: updated, Count: 1})
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if skipDir(path, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		change, err := rewriteImports(path, from, to)
+		if err != nil {
+			return err
+		}
+		if change.Count != 0 {
+			plan.Changes = append(plan.Changes, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return Plan{}, err
+	}
+
+	return plan, nil
+}
+
+// skipDir reports whether the directory at path is not part of the module.
+func skipDir(path, name string) bool {
+	if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return true
+	}
+
+	// A nested module is its own business
+	_, err := os.Stat(filepath.Join(path, Filename))
+	return err == nil
+}
+
+// rewriteImports rewrites any imports of from (or its packages) in the Go file at path to to.
+func rewriteImports(path, from, to string) (Change, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return Change{}, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return Change{}, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	count := 0
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return Change{}, fmt.Errorf("bad import path %s in %s: %w", imp.Path.Value, path, err)
+		}
+
+		if importPath != from && !strings.HasPrefix(importPath, from+"/") {
+			continue
+		}
+
+		imp.Path.Value = strconv.Quote(to + strings.TrimPrefix(importPath, from))
+		count++
+	}
+
+	if count == 0 {
+		return Change{}, nil
+	}
+
+	// The new path might sort differently, gofmt would fix it so we do too
+	ast.SortImports(fset, file)
+
+	buf := &bytes.Buffer{}
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8} //nolint: mnd // Same as gofmt
+	if err := cfg.Fprint(buf, fset, file); err != nil {
+		return Change{}, fmt.Errorf("could not print %s: %w", path, err)
+	}
+
+	return Change{Path: path, Contents: buf.Bytes(), Count: count}, nil
+}
@@ -0,0 +1,129 @@
+package gomod_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.followtheprocess.codes/tag/gomod"
+)
+
+func TestModulePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		major   uint64
+		wantErr bool
+	}{
+		{name: "v1 to v2", path: "example.com/lib", major: 2, want: "example.com/lib/v2"},
+		{name: "v2 to v3", path: "example.com/lib/v2", major: 3, want: "example.com/lib/v3"},
+		{name: "double digits", path: "example.com/lib/v10", major: 11, want: "example.com/lib/v11"},
+		{name: "v0 to v1", path: "example.com/lib", major: 1, want: "example.com/lib"},
+		{name: "same major", path: "example.com/lib/v2", major: 2, want: "example.com/lib/v2"},
+		{name: "v1 is not a suffix", path: "example.com/lib/v1", major: 2, want: "example.com/lib/v1/v2"},
+		{name: "not a version", path: "example.com/lib/vendor", major: 2, want: "example.com/lib/vendor/v2"},
+		{name: "gopkg.in", path: "gopkg.in/yaml.v3", major: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gomod.ModulePath(tt.path, tt.major)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ModulePath() returned %v, wanted error: %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Got %q, wanted %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tmp := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": "module example.com/lib // The best lib\n\ngo 1.26\n",
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/lib/internal/thing"
+	"example.com/library"
+)
+
+func main() {
+	fmt.Println(thing.Name, library.Name) // Hello
+}
+`,
+		"lib.go":                 "package lib\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n",
+		"testdata/bad.go":        "package bad\n\nimport \"example.com/lib\"\n",
+		"nested/go.mod":          "module example.com/lib/nested\n",
+		"nested/nested.go":       "package nested\n\nimport \"example.com/lib\"\n",
+		"internal/thing/go.txt":  "example.com/lib",
+		"internal/thing/self.go": "package thing\n\nimport _ \"example.com/lib\"\n\nconst Name = \"thing\"\n",
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatalf("could not write %s: %v", name, err)
+		}
+	}
+
+	plan, err := gomod.Rewrite(tmp, 2)
+	if err != nil {
+		t.Fatalf("Rewrite returned an error: %v", err)
+	}
+
+	if plan.From != "example.com/lib" || plan.To != "example.com/lib/v2" {
+		t.Errorf("Wrong module paths: got %s -> %s", plan.From, plan.To)
+	}
+
+	got := make(map[string]string, len(plan.Changes))
+	for _, change := range plan.Changes {
+		rel, err := filepath.Rel(tmp, change.Path)
+		if err != nil {
+			t.Fatalf("could not make path relative: %v", err)
+		}
+		got[filepath.ToSlash(rel)] = string(change.Contents)
+	}
+
+	want := map[string]string{
+		"go.mod": "module example.com/lib/v2 // The best lib\n\ngo 1.26\n",
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/lib/v2/internal/thing"
+	"example.com/library"
+)
+
+func main() {
+	fmt.Println(thing.Name, library.Name) // Hello
+}
+`,
+		"internal/thing/self.go": "package thing\n\nimport _ \"example.com/lib/v2\"\n\nconst Name = \"thing\"\n",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got:\n%#v\n\nWanted:\n%#v\n", got, want)
+	}
+
+	// Nothing to do if the major version doesn't need a suffix
+	plan, err = gomod.Rewrite(tmp, 1)
+	if err != nil {
+		t.Fatalf("Rewrite returned an error: %v", err)
+	}
+
+	if len(plan.Changes) != 0 {
+		t.Errorf("Expected no changes moving to v1, got %d", len(plan.Changes))
+	}
+}
@@ -2,12 +2,19 @@
 package hooks
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"os"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
+	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/interp"
 	"mvdan.cc/sh/v3/syntax"
 )
@@ -20,25 +27,128 @@ const (
 	StagePreReplace HookStage = iota // Pre Replace
 	StagePreCommit                   // Pre Commit
 	StagePreTag                      // Pre Tag
+	StagePostTag                     // Post Tag
 	StagePrePush                     // Pre Push
+	StagePostPush                    // Post Push
+	StageOnError                     // On Error
 )
 
-const execTimeout = 10 * time.Second
+// killTimeout is how long a command gets to exit after being interrupted, e.g. by a
+// timeout, before it's killed.
+const killTimeout = 10 * time.Second
 
-// Run runs the hook defined for a given stage, if there is no hook
-// for the given stage, this becomes a no-op.
-func Run(stage HookStage, cmd string, stdout, stderr io.Writer) error {
-	// No-op if there's no cmd, i.e. the hook is not defined
-	if cmd == "" {
-		return nil
+// Key returns the name of the stage as it's written in the config file e.g. "pre-commit".
+func (i HookStage) Key() string {
+	return strings.ToLower(strings.ReplaceAll(i.String(), " ", "-"))
+}
+
+// Vars is the version context a hook runs with.
+//
+// Hook commands may use them as template variables e.g. {{.Next}}, the same as
+// the rest of the config file, and they're exported to the command's environment
+// as TAG_CURRENT, TAG_NEXT, TAG_TAG, TAG_FAILED_STAGE and TAG_ERROR along with
+// TAG_STAGE and TAG_DRY_RUN.
+type Vars struct {
+	Current     string // The version before the bump e.g. "1.2.3"
+	Next        string // The version after the bump e.g. "1.3.0"
+	Tag         string // The tag being issued e.g. "v1.3.0"
+	FailedStage string // Only for on-error, the stage the bump failed at e.g. "pre-commit" or "push"
+	Error       string // Only for on-error, the error that stopped the bump
+}
+
+// Render returns cmd with its template variables filled in from vars.
+func Render(stage HookStage, cmd string, vars Vars) (string, error) {
+	tmp, err := template.New(stage.Key()).Option("missingkey=error").Parse(cmd)
+	if err != nil {
+		return "", fmt.Errorf("could not parse command in hook stage %s: %w", stage, err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := tmp.Execute(out, vars); err != nil {
+		return "", fmt.Errorf("could not execute command in hook stage %s: %w", stage, err)
+	}
+
+	return out.String(), nil
+}
+
+// Command is a single command run by a hook.
+type Command struct {
+	Env             map[string]string // Extra environment variables, the values are rendered like Run
+	Run             string            // The shell command, rendered with [Vars] before it's run
+	Dir             string            // Working directory, empty means the current directory
+	Timeout         time.Duration     // How long the command may take, zero means no limit
+	Capture         string            // If set, the name to keep the command's output under, see [Result]
+	ContinueOnError bool              // Whether a failure should be reported but not stop the hook
+}
+
+// Result is the outcome of a single [Command].
+type Result struct {
+	Err      error         // Why the command failed, nil if it succeeded
+	Command  string        // The command as it was run, after rendering
+	Output   string        // What it wrote to stdout, trimmed of surrounding space, only if it's a Capture
+	Duration time.Duration // How long it took
+}
+
+// Run runs the commands for a hook stage in order, stopping at the first one that
+// fails unless it's marked ContinueOnError. If there are no commands, this becomes
+// a no-op.
+//
+// The results of every command that ran are returned along with the error that
+// stopped the hook, if there was one. The output of a command with a Capture is
+// streamed to stdout as usual and also returned in its result.
+//
+// If ctx is cancelled the running command is interrupted, then killed if it hasn't
+// exited within a few seconds, and the rest are skipped even if it was ContinueOnError.
+func Run(ctx context.Context, stage HookStage, cmds []Command, vars Vars, stdout, stderr io.Writer) ([]Result, error) { //nolint: revive // stdout and stderr are a pair, a struct for them would be clumsier
+	results := make([]Result, 0, len(cmds))
+	for _, cmd := range cmds {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("hook stage %s was interrupted: %w", stage, context.Cause(ctx))
+		}
+		start := time.Now()
+		out := stdout
+		captured := &strings.Builder{}
+		if cmd.Capture != "" {
+			// Still shown as it runs, as well as kept
+			out = io.MultiWriter(stdout, captured)
+		}
+		rendered, err := run(ctx, stage, cmd, vars, out, stderr)
+		results = append(results, Result{
+			Command:  rendered,
+			Output:   strings.TrimSpace(captured.String()),
+			Err:      err,
+			Duration: time.Since(start),
+		})
+		if err != nil && (!cmd.ContinueOnError || ctx.Err() != nil) {
+			return results, err
+		}
 	}
-	prog, err := syntax.NewParser().Parse(strings.NewReader(cmd), "")
+	return results, nil
+}
+
+// run is a helper that renders and runs a single command, returning the rendered command.
+func run(ctx context.Context, stage HookStage, cmd Command, vars Vars, stdout, stderr io.Writer) (string, error) { //nolint: revive // As for Run
+	rendered, err := Render(stage, cmd.Run, vars)
+	if err != nil {
+		return cmd.Run, err
+	}
+
+	env := append(os.Environ(), vars.environ(stage)...)
+	for _, key := range slices.Sorted(maps.Keys(cmd.Env)) {
+		value, err := Render(stage, cmd.Env[key], vars)
+		if err != nil {
+			return rendered, err
+		}
+		env = append(env, key+"="+value)
+	}
+
+	prog, err := syntax.NewParser().Parse(strings.NewReader(rendered), "")
 	if err != nil {
-		return fmt.Errorf("command %q in hook stage %s not valid shell syntax: %w", cmd, stage, err)
+		return rendered, fmt.Errorf("command %q in hook stage %s not valid shell syntax: %w", rendered, stage, err)
 	}
 
 	execHandler := func(interp.ExecHandlerFunc) interp.ExecHandlerFunc {
-		return interp.DefaultExecHandler(execTimeout)
+		return interp.DefaultExecHandler(killTimeout)
 	}
 
 	runner, err := interp.New(
@@ -46,15 +156,43 @@ func Run(stage HookStage, cmd string, stdout, stderr io.Writer) error {
 		interp.ExecHandlers(execHandler),
 		interp.OpenHandler(interp.DefaultOpenHandler()),
 		interp.StdIO(nil, stdout, stderr),
+		interp.Env(expand.ListEnviron(env...)),
+		interp.Dir(cmd.Dir),
 	)
 	if err != nil {
-		return fmt.Errorf("could not configure sh interpreter: %w", err)
+		return rendered, fmt.Errorf("could not configure sh interpreter: %w", err)
 	}
 
-	err = runner.Run(context.Background(), prog)
+	runCtx := ctx
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	err = runner.Run(runCtx, prog)
+	if ctx.Err() != nil {
+		return rendered, fmt.Errorf("command %q in hook stage %s was interrupted: %w", rendered, stage, context.Cause(ctx))
+	}
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return rendered, fmt.Errorf("command %q in hook stage %s timed out after %s", rendered, stage, cmd.Timeout)
+	}
 	if err != nil {
-		return fmt.Errorf("command %q in hook stage %s resulted in an error: %w", cmd, stage, err)
+		return rendered, fmt.Errorf("command %q in hook stage %s resulted in an error: %w", rendered, stage, err)
 	}
 
-	return nil
+	return rendered, nil
+}
+
+// environ returns the environment variables exported to a hook at stage.
+func (v Vars) environ(stage HookStage) []string {
+	return []string{
+		"TAG_CURRENT=" + v.Current,
+		"TAG_NEXT=" + v.Next,
+		"TAG_TAG=" + v.Tag,
+		"TAG_STAGE=" + stage.Key(),
+		"TAG_DRY_RUN=false", // Hooks are only printed in a dry run, so a running one never is
+		"TAG_FAILED_STAGE=" + v.FailedStage,
+		"TAG_ERROR=" + v.Error,
+	}
 }
@@ -2,7 +2,11 @@ package hooks_test
 
 import (
 	"bytes"
+	"context"
+	"os/exec"
+	"strings"
 	"testing"
+	"time"
 
 	"go.followtheprocess.codes/tag/hooks"
 )
@@ -11,7 +15,7 @@ func TestRunOk(t *testing.T) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 
-	if err := hooks.Run(hooks.StagePreCommit, "echo hello there", stdout, stderr); err != nil {
+	if _, err := hooks.Run(t.Context(), hooks.StagePreCommit, []hooks.Command{{Run: "echo hello there"}}, hooks.Vars{}, stdout, stderr); err != nil {
 		t.Fatalf("Run returned an error: %v", err)
 	}
 
@@ -26,7 +30,7 @@ func TestRunNotOk(t *testing.T) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 
-	if err := hooks.Run(hooks.StagePreCommit, "exit 1", stdout, stderr); err == nil {
+	if _, err := hooks.Run(t.Context(), hooks.StagePreCommit, []hooks.Command{{Run: "exit 1"}}, hooks.Vars{}, stdout, stderr); err == nil {
 		t.Fatal("Run did not return an error")
 	}
 }
@@ -35,7 +39,7 @@ func TestRunNoOp(t *testing.T) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 
-	if err := hooks.Run(hooks.StagePreReplace, "", stdout, stderr); err != nil {
+	if _, err := hooks.Run(t.Context(), hooks.StagePreReplace, nil, hooks.Vars{}, stdout, stderr); err != nil {
 		t.Fatalf("Run returned an error: %v", err)
 	}
 
@@ -47,3 +51,138 @@ func TestRunNoOp(t *testing.T) {
 		t.Errorf("Expected no stderr, got %s", stderr.String())
 	}
 }
+
+func TestRunVars(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	vars := hooks.Vars{Current: "1.2.3", Next: "1.3.0", Tag: "api/v1.3.0"}
+	cmd := "echo {{.Current}} {{.Next}} {{.Tag}}; echo $TAG_CURRENT $TAG_NEXT $TAG_TAG $TAG_STAGE $TAG_DRY_RUN"
+
+	if _, err := hooks.Run(t.Context(), hooks.StagePreTag, []hooks.Command{{Run: cmd}}, vars, stdout, stderr); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+
+	want := "1.2.3 1.3.0 api/v1.3.0\n1.2.3 1.3.0 api/v1.3.0 pre-tag false\n"
+
+	if got := stdout.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestRunBadTemplate(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	for _, cmd := range []string{"echo {{.Next", "echo {{.Missing}}"} {
+		if _, err := hooks.Run(t.Context(), hooks.StagePreTag, []hooks.Command{{Run: cmd}}, hooks.Vars{}, stdout, stderr); err == nil {
+			t.Errorf("Run(%q) did not return an error", cmd)
+		}
+	}
+
+	if stdout.String() != "" {
+		t.Errorf("Expected nothing to run, got %s", stdout.String())
+	}
+}
+
+func TestRunCommands(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	dir := t.TempDir()
+
+	commands := []hooks.Command{
+		{Run: "echo first"},
+		{Run: "exit 1", ContinueOnError: true},
+		{Run: "echo $GREETING {{.Next}} from $PWD", Dir: dir, Env: map[string]string{"GREETING": "hello {{.Tag}}"}, Capture: "Greeting"},
+		{Run: "exit 2"},
+		{Run: "echo never"},
+	}
+
+	results, err := hooks.Run(t.Context(), hooks.StagePreTag, commands, hooks.Vars{Next: "1.3.0", Tag: "v1.3.0"}, stdout, stderr)
+	if err == nil {
+		t.Fatal("Run did not return an error")
+	}
+
+	want := "first\nhello v1.3.0 1.3.0 from " + dir + "\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	for i, wantErr := range []bool{false, true, false, true} {
+		if (results[i].Err != nil) != wantErr {
+			t.Errorf("Result %d: got error %v, wanted error: %v", i, results[i].Err, wantErr)
+		}
+	}
+	if results[2].Command != "echo $GREETING 1.3.0 from $PWD" {
+		t.Errorf("Result 2 has the wrong command: %q", results[2].Command)
+	}
+	if results[2].Output != "hello v1.3.0 1.3.0 from "+dir {
+		t.Errorf("Result 2 captured the wrong output: %q", results[2].Output)
+	}
+	if results[0].Output != "" {
+		t.Errorf("Result 0 should not have captured anything, got %q", results[0].Output)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	commands := []hooks.Command{{Run: "sleep 5", Timeout: 50 * time.Millisecond}}
+
+	start := time.Now()
+	_, err := hooks.Run(t.Context(), hooks.StagePreTag, commands, hooks.Vars{}, stdout, stderr)
+	if err == nil {
+		t.Fatal("Run did not return an error")
+	}
+	if !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("Command was not stopped by the timeout, took %s", elapsed)
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	commands := []hooks.Command{
+		{Run: "sleep 5", ContinueOnError: true},
+		{Run: "echo never"},
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results, err := hooks.Run(ctx, hooks.StagePrePush, commands, hooks.Vars{}, stdout, stderr)
+	if err == nil {
+		t.Fatal("Run did not return an error")
+	}
+	if !strings.Contains(err.Error(), `command "sleep 5" in hook stage Pre Push was interrupted`) {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("Command was not interrupted, took %s", elapsed)
+	}
+
+	// Interrupting stops the hook, continue-on-error or not
+	if len(results) != 1 {
+		t.Errorf("Expected 1 result, got %d", len(results))
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("The next command ran: %q", stdout.String())
+	}
+}
@@ -11,12 +11,15 @@ func _() {
 	_ = x[StagePreReplace-0]
 	_ = x[StagePreCommit-1]
 	_ = x[StagePreTag-2]
-	_ = x[StagePrePush-3]
+	_ = x[StagePostTag-3]
+	_ = x[StagePrePush-4]
+	_ = x[StagePostPush-5]
+	_ = x[StageOnError-6]
 }
 
-const _HookStage_name = "Pre ReplacePre CommitPre TagPre Push"
+const _HookStage_name = "Pre ReplacePre CommitPre TagPost TagPre PushPost PushOn Error"
 
-var _HookStage_index = [...]uint8{0, 11, 21, 28, 36}
+var _HookStage_index = [...]uint8{0, 11, 21, 28, 36, 44, 53, 61}
 
 func (i HookStage) String() string {
 	idx := int(i) - 0
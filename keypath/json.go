@@ -0,0 +1,115 @@
+package keypath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// jsonFrame is an open JSON object or array while walking the document.
+type jsonFrame struct {
+	key       string // The current key, for objects
+	index     int    // The current index, for arrays
+	object    bool   // Whether this is an object (or an array)
+	expectKey bool   // Whether the next token in an object is a key
+}
+
+// locateJSON finds the string value at path in a JSON document.
+func locateJSON(contents []byte, path []string) (span, error) {
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+
+	var (
+		stack  []jsonFrame
+		offset int64
+	)
+
+	// done marks the value at the top of the stack as finished
+	done := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := &stack[len(stack)-1]
+		if top.object {
+			top.expectKey = true
+		} else {
+			top.index++
+		}
+	}
+
+	for {
+		before := offset
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return span{}, fmt.Errorf("invalid JSON: %w", err)
+		}
+		offset = decoder.InputOffset()
+
+		if len(stack) != 0 && stack[len(stack)-1].object && stack[len(stack)-1].expectKey {
+			top := &stack[len(stack)-1]
+			if delim, ok := token.(json.Delim); ok && delim == '}' {
+				stack = stack[:len(stack)-1]
+				done()
+				continue
+			}
+			key, ok := token.(string)
+			if !ok {
+				return span{}, fmt.Errorf("invalid JSON: unexpected object key %v", token)
+			}
+			top.key = key
+			top.expectKey = false
+			continue
+		}
+
+		switch value := token.(type) {
+		case json.Delim:
+			switch value {
+			case '{':
+				stack = append(stack, jsonFrame{object: true, expectKey: true})
+			case '[':
+				stack = append(stack, jsonFrame{})
+			case ']', '}':
+				stack = stack[:len(stack)-1]
+				done()
+			}
+		default:
+			if slices.Equal(jsonPath(stack), path) {
+				str, ok := value.(string)
+				if !ok {
+					return span{}, fmt.Errorf("value is %v, not a string", value)
+				}
+
+				// The offset before the token includes any whitespace and separators
+				start := int(before)
+				for start < int(offset) && bytes.IndexByte([]byte(" \t\r\n,:"), contents[start]) != -1 {
+					start++
+				}
+
+				return span{value: str, quote: `"`, start: start, end: int(offset)}, nil
+			}
+			done()
+		}
+	}
+
+	return span{}, ErrNotFound
+}
+
+// jsonPath returns the path to the current value.
+func jsonPath(stack []jsonFrame) []string {
+	path := make([]string, 0, len(stack))
+	for _, frame := range stack {
+		if frame.object {
+			path = append(path, frame.key)
+		} else {
+			path = append(path, strconv.Itoa(frame.index))
+		}
+	}
+	return path
+}
@@ -0,0 +1,160 @@
+// Package keypath implements minimal, format preserving edits of a single string
+// value in a TOML, JSON or YAML document, addressed by a dotted key path
+// e.g. "tool.poetry.version".
+//
+// Rather than decoding and re-encoding the whole document (which loses comments,
+// key order and quoting), the document is parsed only to find the exact location of
+// the value, which is then replaced in place leaving every other byte untouched.
+//
+// Numeric path segments index into arrays, so "module.1.version" refers to the
+// version key of the second element of the module array.
+package keypath
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The supported document formats.
+const (
+	TOML = "toml"
+	JSON = "json"
+	YAML = "yaml"
+)
+
+// ErrNotFound is returned when the key does not exist in the document.
+var ErrNotFound = errors.New("key not found")
+
+// span is the location of a string value in a document.
+type span struct {
+	value string // The decoded value
+	quote string // The quoting style e.g. `"`, `'` or empty for plain YAML scalars
+	start int    // Byte offset of the start of the raw value, including any quotes
+	end   int    // Byte offset of the end of the raw value, including any quotes
+}
+
+// Formats returns the supported formats.
+func Formats() []string {
+	return []string{TOML, JSON, YAML}
+}
+
+// IsSupported reports whether format is a supported document format.
+func IsSupported(format string) bool {
+	return slices.Contains(Formats(), format)
+}
+
+// Get returns the string value at key in contents.
+func Get(format string, contents []byte, key string) (string, error) {
+	s, err := locate(format, contents, key)
+	if err != nil {
+		return "", err
+	}
+	return s.value, nil
+}
+
+// Set returns a copy of contents with the string value at key replaced by value, keeping
+// its original quoting style. Everything else in the document is left exactly as it was.
+func Set(format string, contents []byte, key, value string) ([]byte, error) {
+	s, err := locate(format, contents, key)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := encode(format, s.quote, value)
+	if err != nil {
+		return nil, fmt.Errorf("could not set %s: %w", key, err)
+	}
+
+	out := make([]byte, 0, len(contents)-(s.end-s.start)+len(encoded))
+	out = append(out, contents[:s.start]...)
+	out = append(out, encoded...)
+	out = append(out, contents[s.end:]...)
+	return out, nil
+}
+
+// locate finds the string value at key in contents.
+func locate(format string, contents []byte, key string) (span, error) {
+	path := strings.Split(key, ".")
+	if key == "" || slices.Contains(path, "") {
+		return span{}, fmt.Errorf("invalid key path %q", key)
+	}
+
+	var (
+		s   span
+		err error
+	)
+	switch format {
+	case TOML:
+		s, err = locateTOML(contents, path)
+	case JSON:
+		s, err = locateJSON(contents, path)
+	case YAML:
+		s, err = locateYAML(contents, path)
+	default:
+		return span{}, fmt.Errorf("unsupported format %q, expected one of %s", format, strings.Join(Formats(), ", "))
+	}
+
+	if err != nil {
+		return span{}, fmt.Errorf("%s: %w", key, err)
+	}
+	return s, nil
+}
+
+// encode encodes value as a string in format with the given quoting style.
+func encode(format, quote, value string) (string, error) {
+	switch quote {
+	case "":
+		// Only plain YAML scalars get here, make sure the new value reads back as
+		// the same string when unquoted, otherwise fall back to double quotes
+		if isPlainSafe(value) {
+			return value, nil
+		}
+		return `"` + escape(value) + `"`, nil
+	case `"`, `"""`:
+		return quote + escape(value) + quote, nil
+	case `'`:
+		if format == YAML {
+			return quote + strings.ReplaceAll(value, `'`, `''`) + quote, nil
+		}
+		if strings.ContainsAny(value, "'\n") {
+			return "", fmt.Errorf("value %q cannot be written as a TOML literal string", value)
+		}
+		return quote + value + quote, nil
+	case `'''`:
+		if strings.Contains(value, `'''`) {
+			return "", fmt.Errorf("value %q cannot be written as a TOML multi-line literal string", value)
+		}
+		return quote + value + quote, nil
+	default:
+		return "", fmt.Errorf("unsupported quoting style %s", quote)
+	}
+}
+
+// escape escapes value for use inside a double quoted string, the escaping rules
+// for backslashes and double quotes are the same in all the supported formats.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// isPlainSafe reports whether value can be written as a plain YAML scalar
+// and still be read back as the same string.
+func isPlainSafe(value string) bool {
+	if value == "" || strings.TrimSpace(value) != value {
+		return false
+	}
+	if strings.ContainsAny(value, ":#{}[],&*!|>'\"%@`\n") {
+		return false
+	}
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return false
+	}
+	// e.g. "1.2" would be read back as a float
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return false
+	}
+	return true
+}
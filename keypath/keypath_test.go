@@ -0,0 +1,252 @@
+package keypath_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.followtheprocess.codes/tag/keypath"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		err      error
+		name     string
+		format   string
+		key      string
+		contents string
+		want     string
+		current  string
+		wantErr  bool
+	}{
+		{
+			name:     "json top level",
+			format:   keypath.JSON,
+			key:      "version",
+			contents: "{\n  \"name\": \"thing\",\n  \"version\": \"1.2.3\", \"other\": \"1.2.3\"\n}\n",
+			current:  "1.2.3",
+			want:     "{\n  \"name\": \"thing\",\n  \"version\": \"1.3.0\", \"other\": \"1.2.3\"\n}\n",
+		},
+		{
+			name:     "json nested",
+			format:   keypath.JSON,
+			key:      "packages..version",
+			contents: `{}`,
+			wantErr:  true,
+		},
+		{
+			name:     "json arrays",
+			format:   keypath.JSON,
+			key:      "items.1.version",
+			contents: `{"items": [{"version": "1.2.3"}, {"version":"1.2.3" , "x": [1, {"version": "0"}]}]}`,
+			current:  "1.2.3",
+			want:     `{"items": [{"version": "1.2.3"}, {"version":"1.3.0" , "x": [1, {"version": "0"}]}]}`,
+		},
+		{
+			name:     "json same key deeper",
+			format:   keypath.JSON,
+			key:      "version",
+			contents: `{"dependencies": {"version": "0.0.1"}, "version": "1.2.3"}`,
+			current:  "1.2.3",
+			want:     `{"dependencies": {"version": "0.0.1"}, "version": "1.3.0"}`,
+		},
+		{
+			name:     "json not a string",
+			format:   keypath.JSON,
+			key:      "version",
+			contents: `{"version": 1}`,
+			wantErr:  true,
+		},
+		{
+			name:     "json missing",
+			format:   keypath.JSON,
+			key:      "version",
+			contents: `{"name": "thing"}`,
+			err:      keypath.ErrNotFound,
+			wantErr:  true,
+		},
+		{
+			name:   "toml table",
+			format: keypath.TOML,
+			key:    "tool.poetry.version",
+			contents: `# A comment
+[project]
+version = "0.0.0" # Not this one
+
+[tool.poetry]
+name = 'thing'
+version   =   "1.2.3"  # Keep me
+`,
+			current: "1.2.3",
+			want: `# A comment
+[project]
+version = "0.0.0" # Not this one
+
+[tool.poetry]
+name = 'thing'
+version   =   "1.3.0"  # Keep me
+`,
+		},
+		{
+			name:     "toml dotted key literal string",
+			format:   keypath.TOML,
+			key:      "package.version",
+			contents: "package.name = 'thing'\npackage.version = '1.2.3'\n",
+			current:  "1.2.3",
+			want:     "package.name = 'thing'\npackage.version = '1.3.0'\n",
+		},
+		{
+			name:     "toml inline table",
+			format:   keypath.TOML,
+			key:      "package.version",
+			contents: "package = { name = \"thing\", version = \"1.2.3\" }\n",
+			current:  "1.2.3",
+			want:     "package = { name = \"thing\", version = \"1.3.0\" }\n",
+		},
+		{
+			name:     "toml array of tables",
+			format:   keypath.TOML,
+			key:      "module.1.version",
+			contents: "version = '0.1.0'\n\n[[module]]\nversion = '1.2.3'\n\n[[module]]\nversion = '1.2.3'\n\n[module.hooks]\nversion = 'no'\n",
+			current:  "1.2.3",
+			want:     "version = '0.1.0'\n\n[[module]]\nversion = '1.2.3'\n\n[[module]]\nversion = '1.3.0'\n\n[module.hooks]\nversion = 'no'\n",
+		},
+		{
+			name:     "toml nested table under array",
+			format:   keypath.TOML,
+			key:      "module.1.hooks.version",
+			contents: "[[module]]\n[module.hooks]\nversion = 'a'\n[[module]]\n[module.hooks]\nversion = '1.2.3'\n",
+			current:  "1.2.3",
+			want:     "[[module]]\n[module.hooks]\nversion = 'a'\n[[module]]\n[module.hooks]\nversion = '1.3.0'\n",
+		},
+		{
+			name:     "toml missing",
+			format:   keypath.TOML,
+			key:      "package.version",
+			contents: "[package]\nname = 'thing'\n",
+			err:      keypath.ErrNotFound,
+			wantErr:  true,
+		},
+		{
+			name:     "toml not a string",
+			format:   keypath.TOML,
+			key:      "version",
+			contents: "version = 1\n",
+			wantErr:  true,
+		},
+		{
+			name:   "yaml plain",
+			format: keypath.YAML,
+			key:    "version",
+			contents: `# Chart
+apiVersion: v2
+name: thing
+version: 1.2.3 # Keep me
+appVersion: "1.2.3"
+`,
+			current: "1.2.3",
+			want: `# Chart
+apiVersion: v2
+name: thing
+version: 1.3.0 # Keep me
+appVersion: "1.2.3"
+`,
+		},
+		{
+			name:     "yaml double quoted",
+			format:   keypath.YAML,
+			key:      "appVersion",
+			contents: "version: 1.2.3\nappVersion: \"1.2.3\"\n",
+			current:  "1.2.3",
+			want:     "version: 1.2.3\nappVersion: \"1.3.0\"\n",
+		},
+		{
+			name:   "yaml nested single quoted",
+			format: keypath.YAML,
+			key:    "spec.template.metadata.labels.version",
+			contents: `spec:
+  template:
+    metadata:
+      labels:
+        app: thing
+        version: '1.2.3'
+`,
+			current: "1.2.3",
+			want: `spec:
+  template:
+    metadata:
+      labels:
+        app: thing
+        version: '1.3.0'
+`,
+		},
+		{
+			name:     "yaml sequence",
+			format:   keypath.YAML,
+			key:      "images.1.tag",
+			contents: "images:\n  - tag: 0.0.1\n  - name: ünïcode\n    tag: 1.2.3\n",
+			current:  "1.2.3",
+			want:     "images:\n  - tag: 0.0.1\n  - name: ünïcode\n    tag: 1.3.0\n",
+		},
+		{
+			name:     "yaml not a string",
+			format:   keypath.YAML,
+			key:      "version",
+			contents: "version: 1.2\n",
+			wantErr:  true,
+		},
+		{
+			name:     "yaml missing",
+			format:   keypath.YAML,
+			key:      "version",
+			contents: "name: thing\n",
+			err:      keypath.ErrNotFound,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown format",
+			format:   "xml",
+			key:      "version",
+			contents: "<version>1.2.3</version>",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, err := keypath.Get(tt.format, []byte(tt.contents), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get: err = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Get: got error %v, wanted %v", err, tt.err)
+			}
+
+			if current != tt.current {
+				t.Errorf("Get: got %q, wanted %q", current, tt.current)
+			}
+
+			got, err := keypath.Set(tt.format, []byte(tt.contents), tt.key, "1.3.0")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set: err = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("Set: Got:\n%s\n\nWanted:\n%s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetYAMLQuoting(t *testing.T) {
+	// A plain scalar that would change meaning unquoted must gain quotes
+	got, err := keypath.Set(keypath.YAML, []byte("version: 1.2.3\n"), "version", "true")
+	if err != nil {
+		t.Fatalf("Set returned an error: %v", err)
+	}
+
+	want := "version: \"true\"\n"
+	if string(got) != want {
+		t.Errorf("got %q, wanted %q", string(got), want)
+	}
+}
@@ -0,0 +1,131 @@
+package keypath
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// locateTOML finds the string value at path in a TOML document.
+func locateTOML(contents []byte, path []string) (span, error) {
+	parser := unstable.Parser{}
+	parser.Reset(contents)
+
+	var (
+		table   []string           // The resolved path of the current table
+		indexes = map[string]int{} // Latest index of each array of tables, by resolved path
+		arrays  = map[string]int{} // Index of each array of tables, by unresolved header path
+	)
+
+	for parser.NextExpression() {
+		expr := parser.Expression()
+		switch expr.Kind {
+		case unstable.Table:
+			table = resolveHeader(keyParts(expr.Key()), arrays)
+		case unstable.ArrayTable:
+			parts := keyParts(expr.Key())
+			resolved := resolveHeader(parts, arrays)
+			joined := strings.Join(resolved, ".")
+			index, seen := indexes[joined]
+			if seen {
+				index++
+			}
+			indexes[joined] = index
+			arrays[strings.Join(parts, ".")] = index
+			table = append(resolved, strconv.Itoa(index))
+		case unstable.KeyValue:
+			key := slices.Concat(table, keyParts(expr.Key()))
+			if found, s, err := findTOMLValue(&parser, expr.Value(), key, path); found || err != nil {
+				return s, err
+			}
+		}
+	}
+
+	if err := parser.Error(); err != nil {
+		return span{}, fmt.Errorf("invalid TOML: %w", err)
+	}
+
+	return span{}, ErrNotFound
+}
+
+// findTOMLValue looks for path in the value node at key, descending into inline
+// tables and arrays.
+func findTOMLValue(parser *unstable.Parser, node *unstable.Node, key, path []string) (bool, span, error) {
+	if len(key) > len(path) || !slices.Equal(key, path[:len(key)]) {
+		// Not on the way to what we're looking for
+		return false, span{}, nil
+	}
+
+	switch node.Kind {
+	case unstable.String:
+		if len(key) != len(path) {
+			return false, span{}, nil
+		}
+		raw := parser.Raw(node.Raw)
+		quote := string(raw[:1])
+		if len(raw) >= 6 && (strings.HasPrefix(string(raw), `"""`) || strings.HasPrefix(string(raw), `'''`)) {
+			quote = string(raw[:3])
+		}
+		s := span{
+			value: string(node.Data),
+			quote: quote,
+			start: int(node.Raw.Offset),
+			end:   int(node.Raw.Offset + node.Raw.Length),
+		}
+		return true, s, nil
+	case unstable.InlineTable:
+		children := node.Children()
+		for children.Next() {
+			child := children.Node()
+			childKey := slices.Concat(key, keyParts(child.Key()))
+			if found, s, err := findTOMLValue(parser, child.Value(), childKey, path); found || err != nil {
+				return found, s, err
+			}
+		}
+	case unstable.Array:
+		children := node.Children()
+		index := 0
+		for children.Next() {
+			childKey := append(slices.Clone(key), strconv.Itoa(index))
+			if found, s, err := findTOMLValue(parser, children.Node(), childKey, path); found || err != nil {
+				return found, s, err
+			}
+			index++
+		}
+	default:
+		if len(key) == len(path) {
+			return true, span{}, fmt.Errorf("value is a %s, not a string", node.Kind)
+		}
+	}
+
+	return false, span{}, nil
+}
+
+// resolveHeader resolves a table header's key into a full path, inserting the
+// current index of any arrays of tables it is nested under so that
+// [module.hooks] after the second [[module]] becomes "module.1.hooks".
+func resolveHeader(parts []string, arrays map[string]int) []string {
+	resolved := make([]string, 0, len(parts))
+	for i, part := range parts {
+		resolved = append(resolved, part)
+		if i == len(parts)-1 {
+			break
+		}
+		if index, ok := arrays[strings.Join(parts[:i+1], ".")]; ok {
+			resolved = append(resolved, strconv.Itoa(index))
+		}
+	}
+	return resolved
+}
+
+// keyParts collects the parts of a (possibly dotted) key.
+func keyParts(it unstable.Iterator) []string {
+	var parts []string
+	for it.Next() {
+		parts = append(parts, string(it.Node().Data))
+	}
+	return parts
+}
@@ -0,0 +1,132 @@
+package keypath
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// locateYAML finds the string value at path in a YAML document.
+func locateYAML(contents []byte, path []string) (span, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(contents, &doc); err != nil {
+		return span{}, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return span{}, ErrNotFound
+	}
+
+	node := doc.Content[0]
+	for _, part := range path {
+		next, err := yamlChild(node, part)
+		if err != nil {
+			return span{}, err
+		}
+		node = next
+	}
+
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+		return span{}, fmt.Errorf("value is %s, not a string", node.ShortTag())
+	}
+
+	start, err := yamlOffset(contents, node.Line, node.Column)
+	if err != nil {
+		return span{}, err
+	}
+
+	var (
+		end   int
+		quote string
+	)
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		quote = `"`
+		end = closingQuote(contents, start, '"', '\\')
+	case yaml.SingleQuotedStyle:
+		quote = `'`
+		end = closingQuote(contents, start, '\'', '\'')
+	case 0:
+		// Plain scalar, it's exactly as written
+		end = start + len(node.Value)
+		if end > len(contents) || string(contents[start:end]) != node.Value {
+			return span{}, errors.New("could not locate plain scalar in document")
+		}
+	default:
+		return span{}, errors.New("only plain or quoted scalars can be edited, not block scalars")
+	}
+
+	if end == -1 {
+		return span{}, errors.New("unterminated quoted scalar")
+	}
+
+	return span{value: node.Value, quote: quote, start: start, end: end}, nil
+}
+
+// yamlChild returns the value of key in a mapping, or the element at index key in a sequence.
+func yamlChild(node *yaml.Node, key string) (*yaml.Node, error) {
+	if node.Kind == yaml.AliasNode {
+		return nil, errors.New("cannot edit through an alias")
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1], nil
+			}
+		}
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(key)
+		if err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index], nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// yamlOffset converts a 1-based line and (character) column into a byte offset.
+func yamlOffset(contents []byte, line, column int) (int, error) {
+	offset := 0
+	for range line - 1 {
+		next := bytes.IndexByte(contents[offset:], '\n')
+		if next == -1 {
+			return 0, fmt.Errorf("line %d out of range", line)
+		}
+		offset += next + 1
+	}
+
+	for range column - 1 {
+		if offset >= len(contents) {
+			return 0, fmt.Errorf("column %d out of range", column)
+		}
+		_, size := utf8.DecodeRune(contents[offset:])
+		offset += size
+	}
+
+	return offset, nil
+}
+
+// closingQuote returns the offset just after the quote closing the string
+// that opens at start, or -1 if there isn't one.
+//
+// An escaped quote does not close the string, in single quoted YAML a quote
+// is escaped by doubling it.
+func closingQuote(contents []byte, start int, quote, escape byte) int {
+	for i := start + 1; i < len(contents); i++ {
+		switch {
+		case escape != quote && contents[i] == escape:
+			i++ // Skip whatever is escaped
+		case contents[i] == quote && escape == quote && i+1 < len(contents) && contents[i+1] == quote:
+			i++ // Doubled quote
+		case contents[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}